@@ -0,0 +1,126 @@
+package delta
+
+import "sort"
+
+// AttributeMap holds the formats of an Op, like {"bold": true, "color": "red"}.
+// A nil value means the format is removed when composed on top of another AttributeMap.
+// None of its methods modify the maps they are given, and none of them return nil-valued keys
+// unless the operation keeps them on purpose, like a Diff that removes a format
+type AttributeMap map[string]interface{}
+
+// Keys returns the keys of the map in sorted order, so you can iterate over it deterministically
+func (a AttributeMap) Keys() []string {
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Equal tells you if a and b have the same keys and values, comparing objects,
+// arrays and numbers the way JSON sees them. A nil map is equal to an empty one
+func (a AttributeMap) Equal(b AttributeMap) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		bb, bFound := b[k]
+		if !bFound || !valuesEqual(v, bb) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compose returns the attributes of b on top of the ones of a.
+// Unless keepNil is true, keys set to nil on b remove the key from the result.
+// Object values on b replace the ones on a, see ComposeDeep
+func (a AttributeMap) Compose(b AttributeMap, keepNil bool) AttributeMap {
+	attributes := make(AttributeMap)
+	for _, k := range b.Keys() {
+		if v := b[k]; keepNil || v != nil {
+			attributes[k] = cloneValue(v)
+		}
+	}
+	for _, k := range a.Keys() {
+		if _, bFound := b[k]; !bFound && (keepNil || a[k] != nil) {
+			attributes[k] = cloneValue(a[k])
+		}
+	}
+	return attributes.orNil()
+}
+
+// ComposeDeep is like Compose, but when both a and b have an object for the same key,
+// like {"style": {"color": "red"}}, the objects are merged key by key instead of b replacing a.
+// It's a standalone helper: Delta.Compose, Seq.Compose and the table handler always use Compose,
+// because Transform and Invert treat object values as a whole, and deltas composed with a deep
+// merge would not converge with them
+func (a AttributeMap) ComposeDeep(b AttributeMap, keepNil bool) AttributeMap {
+	return AttributeMap(mergeObjects(a, b, keepNil)).orNil()
+}
+
+// Diff returns the attributes that turn a into b, keys that b doesn't have are set to nil
+func (a AttributeMap) Diff(b AttributeMap) AttributeMap {
+	attributes := make(AttributeMap)
+	for _, k := range a.Keys() {
+		if bb, bFound := b[k]; !bFound {
+			attributes[k] = nil
+		} else if !valuesEqual(a[k], bb) {
+			attributes[k] = cloneValue(bb)
+		}
+	}
+	for _, k := range b.Keys() {
+		if _, aFound := a[k]; !aFound {
+			attributes[k] = cloneValue(b[k])
+		}
+	}
+	return attributes.orNil()
+}
+
+// Transform returns the attributes of b once a has been applied.
+// With priority, a was applied first, so b can only add the keys that a doesn't set
+func (a AttributeMap) Transform(b AttributeMap, priority bool) AttributeMap {
+	if a == nil || !priority {
+		// b simply overwrites us
+		return b.clone().orNil()
+	}
+	attributes := make(AttributeMap)
+	for _, k := range b.Keys() {
+		if _, aFound := a[k]; !aFound {
+			// nil is a valid value
+			attributes[k] = cloneValue(b[k])
+		}
+	}
+	return attributes.orNil()
+}
+
+// Invert returns the attributes that undo a when applied on top of base.
+// Keys changed by a go back to their value on base, and keys that base didn't have are set to nil
+func (a AttributeMap) Invert(base AttributeMap) AttributeMap {
+	attributes := make(AttributeMap)
+	for _, k := range base.Keys() {
+		if aa, aFound := a[k]; aFound && !valuesEqual(aa, base[k]) {
+			attributes[k] = cloneValue(base[k])
+		}
+	}
+	for _, k := range a.Keys() {
+		if _, bFound := base[k]; !bFound {
+			attributes[k] = nil
+		}
+	}
+	return attributes.orNil()
+}
+
+// clone returns a deep copy of the map that can be changed without affecting a
+func (a AttributeMap) clone() AttributeMap {
+	return AttributeMap(cloneObject(a))
+}
+
+// orNil returns nil for an empty map, so ops without attributes don't carry an empty one around
+func (a AttributeMap) orNil() AttributeMap {
+	if len(a) == 0 {
+		return nil
+	}
+	return a
+}
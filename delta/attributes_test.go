@@ -0,0 +1,182 @@
+package delta
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAttributeMapKeys(t *testing.T) {
+	attr := AttributeMap{"color": "red", "bold": true, "align": nil}
+	expected := []string{"align", "bold", "color"}
+	if x := attr.Keys(); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if x := AttributeMap(nil).Keys(); len(x) != 0 {
+		t.Errorf("expected no keys but got %+v\n", x)
+	}
+}
+
+func TestAttributeMapCompose(t *testing.T) {
+	a := AttributeMap{"bold": true, "color": "red"}
+	b := AttributeMap{"color": "blue", "italic": nil, "font": nil}
+
+	expected := AttributeMap{"bold": true, "color": "blue"}
+	if x := a.Compose(b, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	expected = AttributeMap{"bold": true, "color": "blue", "italic": nil, "font": nil}
+	if x := a.Compose(b, true); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if x := AttributeMap(nil).Compose(AttributeMap{"bold": nil}, false); x != nil {
+		t.Errorf("expected nil but got %+v\n", x)
+	}
+}
+
+func TestAttributeMapComposeDoesNotModifyInputs(t *testing.T) {
+	a := AttributeMap{"bold": true, "color": "red"}
+	b := AttributeMap{"color": "blue", "italic": nil}
+
+	x := a.Compose(b, false)
+	x["size"] = "12px"
+	if !reflect.DeepEqual(AttributeMap{"bold": true, "color": "red"}, a) {
+		t.Errorf("expected a to be unchanged but got %+v\n", a)
+	}
+	if !reflect.DeepEqual(AttributeMap{"color": "blue", "italic": nil}, b) {
+		t.Errorf("expected b to be unchanged but got %+v\n", b)
+	}
+}
+
+func TestAttributeMapDiff(t *testing.T) {
+	a := AttributeMap{"bold": true, "color": "red"}
+	b := AttributeMap{"bold": true, "color": "blue", "italic": true}
+	expected := AttributeMap{"color": "blue", "italic": true}
+	if x := a.Diff(b); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	expected = AttributeMap{"color": "red", "italic": nil}
+	if x := b.Diff(a); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if x := a.Diff(a); x != nil {
+		t.Errorf("expected nil but got %+v\n", x)
+	}
+}
+
+func TestAttributeMapTransform(t *testing.T) {
+	a := AttributeMap{"bold": true, "color": "red", "font": nil}
+	b := AttributeMap{"color": "blue", "font": "serif", "italic": true}
+
+	expected := AttributeMap{"italic": true}
+	if x := a.Transform(b, true); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if x := a.Transform(b, false); !reflect.DeepEqual(b, x) {
+		t.Errorf("expected %+v but got %+v\n", b, x)
+	}
+	if x := a.Transform(nil, true); x != nil {
+		t.Errorf("expected nil but got %+v\n", x)
+	}
+}
+
+func TestAttributeMapTransformDoesNotAlias(t *testing.T) {
+	b := AttributeMap{"color": "blue"}
+	x := AttributeMap(nil).Transform(b, true)
+	x["color"] = "red"
+	if b["color"] != "blue" {
+		t.Errorf("expected b to be unchanged but got %+v\n", b)
+	}
+}
+
+func TestAttributeMapInvert(t *testing.T) {
+	attr := AttributeMap{"bold": true, "italic": nil, "color": "red", "size": "12px"}
+	base := AttributeMap{"font": "serif", "italic": true, "color": "blue", "size": "12px"}
+	expected := AttributeMap{"bold": nil, "italic": true, "color": "blue"}
+	if x := attr.Invert(base); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if x := attr.Invert(attr); x != nil {
+		t.Errorf("expected nil but got %+v\n", x)
+	}
+}
+
+func TestAttributeMapJSON(t *testing.T) {
+	in := []byte(`{"ops":[{"insert":"a","attributes":{"bold":true,"color":"red"}}]}`)
+	d, err := FromJSON(in)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if d.Ops[0].Attributes["color"] != "red" {
+		t.Errorf("expected 'color: red' but got %+v\n", d.Ops[0].Attributes)
+	}
+	out, err := json.Marshal(d)
+	if err != nil {
+		t.Error("failed to get json string, err: ", err)
+	}
+	if string(in) != string(out) {
+		t.Errorf("expected:\n'%+v' but got :\n'%+v'\n", string(in), string(out))
+	}
+}
+
+func TestAttributeMapEqual(t *testing.T) {
+	a := AttributeMap{"font": map[string]interface{}{"family": "Helvetica", "size": float64(15)}}
+	b := AttributeMap{"font": map[string]interface{}{"size": 15, "family": "Helvetica"}}
+	if !a.Equal(b) {
+		t.Errorf("expected %+v to equal %+v\n", a, b)
+	}
+	if !AttributeMap(nil).Equal(AttributeMap{}) {
+		t.Error("expected nil to equal an empty map")
+	}
+	if a.Equal(AttributeMap{"font": nil}) {
+		t.Errorf("expected %+v not to equal {font: nil}\n", a)
+	}
+}
+
+func TestAttributeMapDiffObjects(t *testing.T) {
+	a := AttributeMap{"font": map[string]interface{}{"family": "Helvetica", "size": "15px"}, "list": []interface{}{"a"}}
+	b := AttributeMap{"font": map[string]interface{}{"family": "Helvetica", "size": "15px"}, "list": []interface{}{"a"}}
+	if x := a.Diff(b); x != nil {
+		t.Errorf("expected nil but got %+v\n", x)
+	}
+	c := AttributeMap{"font": map[string]interface{}{"family": "Arial", "size": "15px"}, "list": []interface{}{"a"}}
+	expected := AttributeMap{"font": map[string]interface{}{"family": "Arial", "size": "15px"}}
+	x := a.Diff(c)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	x["font"].(map[string]interface{})["family"] = "Courier"
+	if c["font"].(map[string]interface{})["family"] != "Arial" {
+		t.Errorf("expected c to be unchanged but got %+v\n", c)
+	}
+}
+
+func TestAttributeMapInvertObjects(t *testing.T) {
+	attr := AttributeMap{"font": map[string]interface{}{"family": "Helvetica"}}
+	base := AttributeMap{"font": map[string]interface{}{"family": "Helvetica"}}
+	if x := attr.Invert(base); x != nil {
+		t.Errorf("expected nil but got %+v\n", x)
+	}
+	base = AttributeMap{"font": map[string]interface{}{"family": "Arial"}}
+	if x := attr.Invert(base); !reflect.DeepEqual(base, x) {
+		t.Errorf("expected %+v but got %+v\n", base, x)
+	}
+}
+
+func TestAttributeMapComposeDeep(t *testing.T) {
+	a := AttributeMap{"style": map[string]interface{}{"color": "red", "size": "12px"}, "bold": true}
+	b := AttributeMap{"style": map[string]interface{}{"size": nil, "font": "serif"}, "bold": nil}
+	expected := AttributeMap{"style": map[string]interface{}{"color": "red", "font": "serif"}}
+	if x := a.ComposeDeep(b, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	// Compose replaces the object instead
+	expected = AttributeMap{"style": map[string]interface{}{"size": nil, "font": "serif"}}
+	if x := a.Compose(b, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	remove := AttributeMap{"style": map[string]interface{}{"color": nil, "size": nil}, "bold": nil}
+	if x := a.ComposeDeep(remove, false); x != nil {
+		t.Errorf("expected nil but got %+v\n", x)
+	}
+}
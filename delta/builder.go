@@ -0,0 +1,69 @@
+package delta
+
+import "slices"
+
+// Builder builds a Delta in place, for hot paths where the copies made by Delta.Push are too slow.
+// Unlike the methods of Delta, it keeps what you give it: the text, attributes and embeds become
+// part of the built Delta. Merging two inserts copies the text of the first one once, then appends to the copy.
+// Don't change or reuse them after passing them to a Builder, and don't use the Builder after calling Delta
+type Builder struct {
+	delta Delta
+}
+
+// NewBuilder returns an empty Builder with room for capacity ops
+func NewBuilder(capacity int) *Builder {
+	return &Builder{delta: Delta{Ops: make([]Op, 0, capacity)}}
+}
+
+// Push adds op to the Delta being built, merging it with the last op like Delta.Push does
+func (b *Builder) Push(op Op) *Builder {
+	// the first merge into op's text must allocate, not append into the caller's slice
+	op.Insert = slices.Clip(op.Insert)
+	b.delta.push(op)
+	return b
+}
+
+// Insert adds text with the given attributes, an empty text is ignored
+func (b *Builder) Insert(text string, attrs AttributeMap) *Builder {
+	if text == "" {
+		return b
+	}
+	return b.Push(Op{Insert: []rune(text), Attributes: attrs.orNil()})
+}
+
+// InsertEmbed adds embed with the given attributes, a nil embed is ignored
+func (b *Builder) InsertEmbed(embed Embed, attrs AttributeMap) *Builder {
+	if embed == nil {
+		return b
+	}
+	return b.Push(Op{InsertEmbed: embed, Attributes: attrs.orNil()})
+}
+
+// Retain keeps n characters and applies attrs to them, n <= 0 is ignored
+func (b *Builder) Retain(n int, attrs AttributeMap) *Builder {
+	if n <= 0 {
+		return b
+	}
+	return b.Push(Op{Retain: &n, Attributes: attrs.orNil()})
+}
+
+// RetainEmbed keeps one embed and applies the change in embed to it, a nil embed is ignored
+func (b *Builder) RetainEmbed(embed Embed, attrs AttributeMap) *Builder {
+	if embed == nil {
+		return b
+	}
+	return b.Push(Op{RetainEmbed: embed, Attributes: attrs.orNil()})
+}
+
+// Delete removes n characters, n <= 0 is ignored
+func (b *Builder) Delete(n int) *Builder {
+	if n <= 0 {
+		return b
+	}
+	return b.Push(Op{Delete: &n})
+}
+
+// Delta returns the built Delta, it shares its ops with the Builder
+func (b *Builder) Delta() *Delta {
+	return &b.delta
+}
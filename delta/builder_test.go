@@ -0,0 +1,38 @@
+package delta
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	bold := AttributeMap{"bold": true}
+	x := NewBuilder(4).
+		Insert("Hello", bold).
+		Insert(" World", bold).
+		Insert("", nil).
+		InsertEmbed(Embed{"image": "a.png"}, AttributeMap{}).
+		Retain(2, nil).
+		RetainEmbed(Embed{"table": nil}, nil).
+		Delete(1).
+		Delete(2).
+		Delta()
+	expected := New(nil).
+		Insert("Hello World", bold).
+		InsertEmbed(Embed{"image": "a.png"}, nil).
+		Retain(2, nil).
+		RetainEmbed(Embed{"table": nil}, nil).
+		Delete(3)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestBuilderKeepsAttributes(t *testing.T) {
+	bold := AttributeMap{"bold": true}
+	x := NewBuilder(0).Insert("a", bold).Delta()
+	bold["italic"] = true
+	if _, found := x.Ops[0].Attributes["italic"]; !found {
+		t.Error("expected the Builder to keep the attributes it was given")
+	}
+}
@@ -0,0 +1,77 @@
+package delta
+
+import "iter"
+
+// Filter returns a new Delta with the ops for which fn returns true
+func (d *Delta) Filter(fn func(op Op, index int) bool) *Delta {
+	delta := New(nil)
+	for i, op := range d.Ops {
+		if fn(op, i) {
+			delta.Push(op)
+		}
+	}
+	return delta
+}
+
+// Map returns a new Delta with the ops returned by fn, ops for which fn returns a nil Op are dropped
+func (d *Delta) Map(fn func(op Op, index int) Op) *Delta {
+	delta := New(nil)
+	for i, op := range d.Ops {
+		if newOp := fn(op, i); !newOp.IsNil() {
+			delta.Push(newOp)
+		}
+	}
+	return delta
+}
+
+// Partition splits the ops in two new Deltas, the ones for which fn returns true, and the rest
+func (d *Delta) Partition(fn func(op Op, index int) bool) (*Delta, *Delta) {
+	passed, failed := New(nil), New(nil)
+	for i, op := range d.Ops {
+		if fn(op, i) {
+			passed.Push(op)
+		} else {
+			failed.Push(op)
+		}
+	}
+	return passed, failed
+}
+
+// ForEach calls fn for every op of the Delta
+func (d *Delta) ForEach(fn func(op Op, index int)) {
+	for i, op := range d.Ops {
+		fn(op, i)
+	}
+}
+
+// Reduce calls fn for every op of the Delta, passing the value returned for the previous op,
+// or initial for the first one, and returns the last value.
+// It's a function and not a method because Go methods can't have type parameters
+func Reduce[T any](d *Delta, fn func(acc T, op Op, index int) T, initial T) T {
+	acc := initial
+	for i, op := range d.Ops {
+		acc = fn(acc, op, i)
+	}
+	return acc
+}
+
+// All returns an iterator over the index and op of every op of the Delta
+func (d *Delta) All() iter.Seq2[int, Op] {
+	return func(yield func(int, Op) bool) {
+		for i, op := range d.Ops {
+			if !yield(i, op) {
+				return
+			}
+		}
+	}
+}
+
+// Lines returns an iterator over the lines of the document, with the attributes of the newline
+// that ends each of them, see EachLine
+func (d *Delta) Lines() iter.Seq2[Delta, AttributeMap] {
+	return func(yield func(Delta, AttributeMap) bool) {
+		d.EachLine(func(line Delta, attrs map[string]interface{}, index int) bool {
+			return yield(line, attrs)
+		}, "\n")
+	}
+}
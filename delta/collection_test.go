@@ -0,0 +1,122 @@
+package delta
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	embed := Embed{"image": "http://quilljs.com"}
+	delta := New(nil).Insert("Hello", nil).InsertEmbed(embed, nil).Insert("World!", nil)
+	x := delta.Filter(func(op Op, index int) bool {
+		return op.Insert != nil
+	})
+	// the two text inserts are merged once the embed is gone
+	expected := New(nil).Insert("HelloWorld!", nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if len(delta.Ops) != 3 {
+		t.Errorf("expected the original delta to keep 3 ops but got %+v\n", delta.Ops)
+	}
+}
+
+func TestMap(t *testing.T) {
+	bold := AttributeMap{"bold": true}
+	delta := New(nil).Insert("Hello", bold).Retain(2, nil).Delete(1)
+	x := delta.Map(func(op Op, index int) Op {
+		if op.Delete != nil {
+			return Op{}
+		}
+		op.Attributes = nil
+		return op
+	})
+	expected := New(nil).Insert("Hello", nil).Retain(2, nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if delta.Ops[0].Attributes == nil {
+		t.Errorf("expected the original delta to keep its attributes but got %+v\n", delta.Ops)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	embed := Embed{"image": "http://quilljs.com"}
+	delta := New(nil).Insert("Hello", nil).InsertEmbed(embed, nil).Insert("World!", nil)
+	passed, failed := delta.Partition(func(op Op, index int) bool {
+		return op.InsertEmbed != nil
+	})
+	expected := New(nil).InsertEmbed(embed, nil)
+	if !reflect.DeepEqual(expected, passed) {
+		t.Errorf("expected %+v but got %+v\n", expected, passed)
+	}
+	expected = New(nil).Insert("HelloWorld!", nil)
+	if !reflect.DeepEqual(expected, failed) {
+		t.Errorf("expected %+v but got %+v\n", expected, failed)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	delta := New(nil).Insert("Hello", nil).Retain(2, nil).Delete(1)
+	var indexes []int
+	delta.ForEach(func(op Op, index int) {
+		indexes = append(indexes, index)
+	})
+	if !reflect.DeepEqual([]int{0, 1, 2}, indexes) {
+		t.Errorf("expected [0 1 2] but got %+v\n", indexes)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	embed := Embed{"image": "http://quilljs.com"}
+	delta := New(nil).Insert("Hello", nil).InsertEmbed(embed, nil).Insert("World!", AttributeMap{"bold": true})
+	text := Reduce(delta, func(acc string, op Op, index int) string {
+		return acc + string(op.Insert)
+	}, "")
+	if text != "HelloWorld!" {
+		t.Errorf("expected 'HelloWorld!' but got '%s'\n", text)
+	}
+	length := Reduce(delta, func(acc int, op Op, index int) int {
+		return acc + OpsLength(op)
+	}, 0)
+	if length != 12 {
+		t.Error("expected 12 but got ", length)
+	}
+}
+
+func TestAll(t *testing.T) {
+	delta := New(nil).Insert("Hello", nil).Retain(2, nil).Delete(1)
+	count := 0
+	for i, op := range delta.All() {
+		if !reflect.DeepEqual(delta.Ops[i], op) {
+			t.Errorf("expected %+v but got %+v\n", delta.Ops[i], op)
+		}
+		count++
+		if i == 1 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Error("expected 2 iterations but got ", count)
+	}
+}
+
+func TestLines(t *testing.T) {
+	align := AttributeMap{"align": "right"}
+	delta := New(nil).Insert("Hello\n", nil).Insert("World", nil).Insert("\n", align).Insert("!", nil)
+	var lines []string
+	var attrs []AttributeMap
+	for line, attr := range delta.Lines() {
+		lines = append(lines, string(line.Ops[0].Insert))
+		attrs = append(attrs, attr)
+		if len(lines) == 2 {
+			break
+		}
+	}
+	if !reflect.DeepEqual([]string{"Hello", "World"}, lines) {
+		t.Errorf("expected [Hello World] but got %+v\n", lines)
+	}
+	if !reflect.DeepEqual([]AttributeMap{nil, align}, attrs) {
+		t.Errorf("expected [nil align] but got %+v\n", attrs)
+	}
+}
@@ -0,0 +1,56 @@
+package delta
+
+import (
+	"runtime"
+	"sync"
+)
+
+// composeAllSerial is the number of deltas under which ComposeAll doesn't start new goroutines,
+// composing a few small deltas is faster than scheduling them
+const composeAllSerial = 16
+
+// ComposeAll returns the same Delta as composing deltas from left to right,
+// deltas[0].Compose(deltas[1]).Compose(deltas[2])..., but composes them in pairs as a balanced tree.
+// Each Compose then works on deltas of similar size, instead of the growing result being rebuilt
+// for every delta, and the branches of the tree are composed in parallel.
+// Like Compose, it panics if a change to an embed has no EmbedHandler
+func ComposeAll(deltas []Delta) *Delta {
+	if len(deltas) == 0 {
+		return New(nil)
+	}
+	return composeTree(deltas, runtime.GOMAXPROCS(0))
+}
+
+// composeTree composes deltas, using up to workers goroutines
+func composeTree(deltas []Delta, workers int) *Delta {
+	switch len(deltas) {
+	case 1:
+		return deltas[0].Clone()
+	case 2:
+		return deltas[0].Compose(deltas[1])
+	}
+	mid := len(deltas) / 2
+	if workers < 2 || len(deltas) < composeAllSerial {
+		left := composeTree(deltas[:mid], 1)
+		return left.Compose(*composeTree(deltas[mid:], 1))
+	}
+
+	var left *Delta
+	var recovered interface{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// a panic can't cross goroutines, so hand it back to the caller
+		defer func() {
+			recovered = recover()
+		}()
+		left = composeTree(deltas[:mid], workers/2)
+	}()
+	right := composeTree(deltas[mid:], workers-workers/2)
+	wg.Wait()
+	if recovered != nil {
+		panic(recovered)
+	}
+	return left.Compose(*right)
+}
@@ -0,0 +1,121 @@
+package delta
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomChange returns a change that can be applied to a document of the given length
+func randomChange(r *rand.Rand, length int) *Delta {
+	delta := New(nil)
+	attrs := []AttributeMap{nil, {"bold": true}, {"bold": nil}, {"color": "red"}}
+	for length > 0 {
+		n := r.Intn(length) + 1
+		switch r.Intn(4) {
+		case 0:
+			delta.Retain(n, attrs[r.Intn(len(attrs))])
+		case 1:
+			delta.Delete(n)
+		case 2:
+			delta.Insert("abc"[:r.Intn(3)+1], attrs[r.Intn(2)])
+			n = 0
+		default:
+			delta.Retain(n, nil)
+		}
+		length -= n
+	}
+	if r.Intn(2) == 0 {
+		delta.Insert("xyz", nil)
+	}
+	return delta.Chop()
+}
+
+// randomHistory returns a document followed by n changes that apply on top of each other
+func randomHistory(r *rand.Rand, n int) []Delta {
+	doc := New(nil).Insert("Hello World\n", nil)
+	history := []Delta{*doc}
+	for i := 0; i < n; i++ {
+		change := randomChange(r, doc.Length())
+		history = append(history, *change)
+		doc = doc.Compose(*change)
+	}
+	return history
+}
+
+func composeLoop(deltas []Delta) *Delta {
+	ret := deltas[0].Clone()
+	for _, d := range deltas[1:] {
+		ret = ret.Compose(d)
+	}
+	return ret
+}
+
+func TestComposeAll(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 2, 3, 15, 16, 17, 100, 257} {
+		history := randomHistory(r, n)
+		expected := composeLoop(history)
+		if x := ComposeAll(history); !reflect.DeepEqual(expected, x) {
+			t.Errorf("%d: expected %+v but got %+v\n", n, expected, x)
+		}
+	}
+}
+
+func TestComposeAllChanges(t *testing.T) {
+	// without a document first, changes compose into a change
+	r := rand.New(rand.NewSource(2))
+	history := randomHistory(r, 50)[1:]
+	expected := composeLoop(history)
+	if x := ComposeAll(history); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestComposeAllEmpty(t *testing.T) {
+	if x := ComposeAll(nil); !reflect.DeepEqual(New(nil), x) {
+		t.Errorf("expected an empty delta but got %+v\n", x)
+	}
+}
+
+func TestComposeAllPanics(t *testing.T) {
+	deltas := make([]Delta, 0, 64)
+	deltas = append(deltas, *New(nil).InsertEmbed(Embed{"image": "a.png"}, nil))
+	for i := 0; i < 62; i++ {
+		deltas = append(deltas, *New(nil).Retain(1, nil).Insert("a", nil))
+	}
+	deltas = append(deltas, *New(nil).RetainEmbed(Embed{"image": "b.png"}, nil))
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrNoEmbedHandler) {
+			t.Errorf("expected ErrNoEmbedHandler but got %+v\n", err)
+		}
+	}()
+	ComposeAll(deltas)
+}
+
+// typingHistory is a document typed one character at a time
+func typingHistory(n int) []Delta {
+	history := make([]Delta, n)
+	for i := range history {
+		history[i] = *New(nil).Retain(i, nil).Insert("a", nil)
+	}
+	return history
+}
+
+func BenchmarkComposeLoop(b *testing.B) {
+	history := typingHistory(2000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		composeLoop(history)
+	}
+}
+
+func BenchmarkComposeAll(b *testing.B) {
+	history := typingHistory(2000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ComposeAll(history)
+	}
+}
@@ -0,0 +1,187 @@
+package delta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// ErrUnknownField is used by DecodeStrict for keys that a Delta or an Op don't have
+var ErrUnknownField = errors.New("unknown field")
+
+// ErrMissingField is used by DecodeStrict when a required key is not there
+var ErrMissingField = errors.New("missing field")
+
+// ErrWrongType is used by DecodeStrict when a value has the wrong JSON type, like "insert": 5
+var ErrWrongType = errors.New("wrong type")
+
+// ErrInvalidNumber is used by DecodeStrict for lengths that are fractional or don't fit in an int
+var ErrInvalidNumber = errors.New("invalid number")
+
+// DecodeError tells you where DecodeStrict found a problem, as a JSON path like ops[3].retain
+type DecodeError struct {
+	Path string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns Err, so errors.Is(err, ErrWrongType) and friends work on a *DecodeError
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeStrict is like FromJSON but for deltas you don't trust, like the ones sent by a client.
+// It rejects unknown fields, values of the wrong type, empty inserts, lengths that are not
+// positive integers and ops with more than one of insert, retain and delete.
+// Errors are *DecodeError values
+func DecodeStrict(in []byte) (*Delta, error) {
+	decoder := json.NewDecoder(bytes.NewReader(in))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, &DecodeError{Err: err}
+	}
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return nil, &DecodeError{Err: errors.New("unexpected data after the delta")}
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, &DecodeError{Err: fmt.Errorf("%w: want an object", ErrWrongType)}
+	}
+	if err := checkFields(object, "", "ops"); err != nil {
+		return nil, err
+	}
+	rawOps, found := object["ops"]
+	if !found {
+		return nil, &DecodeError{Path: "ops", Err: ErrMissingField}
+	}
+	list, ok := rawOps.([]interface{})
+	if !ok {
+		return nil, &DecodeError{Path: "ops", Err: fmt.Errorf("%w: want an array", ErrWrongType)}
+	}
+
+	delta := New(nil)
+	for i, rawOp := range list {
+		op, err := decodeStrictOp(rawOp, fmt.Sprintf("ops[%d]", i))
+		if err != nil {
+			return nil, err
+		}
+		delta.Ops = append(delta.Ops, op)
+	}
+	return delta, nil
+}
+
+// decodeStrictOp turns the decoded JSON value of an op into an Op, path is where the op is
+func decodeStrictOp(value interface{}, path string) (Op, error) {
+	var op Op
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return op, &DecodeError{Path: path, Err: fmt.Errorf("%w: want an object", ErrWrongType)}
+	}
+	if err := checkFields(object, path, "insert", "retain", "delete", "attributes"); err != nil {
+		return op, err
+	}
+
+	var kinds []string
+	for _, k := range []string{"insert", "retain", "delete"} {
+		if _, found := object[k]; found {
+			kinds = append(kinds, k)
+		}
+	}
+	if len(kinds) == 0 {
+		return op, &DecodeError{Path: path, Err: fmt.Errorf("%w: want one of insert, retain or delete", ErrMissingField)}
+	}
+	if len(kinds) > 1 {
+		return op, &DecodeError{Path: path, Err: fmt.Errorf("%w: %v", ErrMultipleOpKinds, kinds)}
+	}
+
+	kind, kindPath := kinds[0], path+"."+kinds[0]
+	switch v := object[kind].(type) {
+	case string:
+		if kind != "insert" {
+			return op, &DecodeError{Path: kindPath, Err: fmt.Errorf("%w: want a positive integer", ErrWrongType)}
+		}
+		if v == "" {
+			return op, &DecodeError{Path: kindPath, Err: ErrNonPositiveLength}
+		}
+		op.Insert = []rune(v)
+	case map[string]interface{}:
+		if kind == "delete" {
+			return op, &DecodeError{Path: kindPath, Err: fmt.Errorf("%w: want a positive integer", ErrWrongType)}
+		}
+		if len(v) != 1 {
+			return op, &DecodeError{Path: kindPath, Err: fmt.Errorf("%w: an embed needs exactly one key", ErrWrongType)}
+		}
+		if kind == "insert" {
+			op.InsertEmbed = Embed(v)
+		} else {
+			op.RetainEmbed = Embed(v)
+		}
+	case json.Number:
+		if kind == "insert" {
+			return op, &DecodeError{Path: kindPath, Err: fmt.Errorf("%w: want a string or an object", ErrWrongType)}
+		}
+		n, err := strconv.Atoi(v.String())
+		if err != nil {
+			return op, &DecodeError{Path: kindPath, Err: fmt.Errorf("%w: %s", ErrInvalidNumber, v)}
+		}
+		if n <= 0 {
+			return op, &DecodeError{Path: kindPath, Err: ErrNonPositiveLength}
+		}
+		if kind == "retain" {
+			op.Retain = &n
+		} else {
+			op.Delete = &n
+		}
+	default:
+		want := "a positive integer"
+		if kind == "insert" {
+			want = "a string or an object"
+		} else if kind == "retain" {
+			want = "a positive integer or an object"
+		}
+		return op, &DecodeError{Path: kindPath, Err: fmt.Errorf("%w: want %s", ErrWrongType, want)}
+	}
+
+	if rawAttrs, found := object["attributes"]; found {
+		attrs, ok := rawAttrs.(map[string]interface{})
+		if !ok {
+			return op, &DecodeError{Path: path + ".attributes", Err: fmt.Errorf("%w: want an object", ErrWrongType)}
+		}
+		op.Attributes = AttributeMap(attrs).orNil()
+	}
+	return op, nil
+}
+
+// checkFields returns a *DecodeError for the first key of object, in sorted order, that is not in allowed
+func checkFields(object map[string]interface{}, path string, allowed ...string) error {
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		known := false
+		for _, a := range allowed {
+			known = known || k == a
+		}
+		if !known {
+			if path != "" {
+				k = path + "." + k
+			}
+			return &DecodeError{Path: k, Err: ErrUnknownField}
+		}
+	}
+	return nil
+}
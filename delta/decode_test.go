@@ -0,0 +1,76 @@
+package delta
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	in := `{"ops":[{"insert":"Hello","attributes":{"bold":true}},{"insert":{"image":"a.png"}},{"retain":3},{"retain":{"table":{"rows":1}}},{"delete":2}]}`
+	expected := New(nil).
+		Insert("Hello", map[string]interface{}{"bold": true}).
+		InsertEmbed(Embed{"image": "a.png"}, nil).
+		Retain(3, nil).
+		RetainEmbed(Embed{"table": map[string]interface{}{"rows": json.Number("1")}}, nil).
+		Delete(2)
+	x, err := DecodeStrict([]byte(in))
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestDecodeStrictErrors(t *testing.T) {
+	cases := []struct {
+		in     string
+		path   string
+		target error
+	}{
+		{`{"ops":[{"insert":"a"},{"retain":1,"foo":1}]}`, "ops[1].foo", ErrUnknownField},
+		{`{"ops":[],"extra":true}`, "extra", ErrUnknownField},
+		{`{}`, "ops", ErrMissingField},
+		{`{"ops":{}}`, "ops", ErrWrongType},
+		{`[]`, "", ErrWrongType},
+		{`{"ops":[5]}`, "ops[0]", ErrWrongType},
+		{`{"ops":[{"attributes":{"bold":true}}]}`, "ops[0]", ErrMissingField},
+		{`{"ops":[{"insert":"a","delete":1}]}`, "ops[0]", ErrMultipleOpKinds},
+		{`{"ops":[{"insert":5}]}`, "ops[0].insert", ErrWrongType},
+		{`{"ops":[{"insert":""}]}`, "ops[0].insert", ErrNonPositiveLength},
+		{`{"ops":[{"insert":null}]}`, "ops[0].insert", ErrWrongType},
+		{`{"ops":[{"insert":{}}]}`, "ops[0].insert", ErrWrongType},
+		{`{"ops":[{"retain":"3"}]}`, "ops[0].retain", ErrWrongType},
+		{`{"ops":[{"retain":1},{"retain":1,"attributes":{}},{"retain":1.5}]}`, "ops[2].retain", ErrInvalidNumber},
+		{`{"ops":[{"retain":1e400}]}`, "ops[0].retain", ErrInvalidNumber},
+		{`{"ops":[{"retain":99999999999999999999}]}`, "ops[0].retain", ErrInvalidNumber},
+		{`{"ops":[{"retain":0}]}`, "ops[0].retain", ErrNonPositiveLength},
+		{`{"ops":[{"delete":-1}]}`, "ops[0].delete", ErrNonPositiveLength},
+		{`{"ops":[{"delete":{"table":1}}]}`, "ops[0].delete", ErrWrongType},
+		{`{"ops":[{"insert":"a","attributes":[]}]}`, "ops[0].attributes", ErrWrongType},
+	}
+	for _, c := range cases {
+		_, err := DecodeStrict([]byte(c.in))
+		var decodeErr *DecodeError
+		if !errors.Is(err, c.target) || !errors.As(err, &decodeErr) || decodeErr.Path != c.path {
+			t.Errorf("%s: expected %v at %q but got %v\n", c.in, c.target, c.path, err)
+		}
+	}
+}
+
+func TestDecodeStrictSyntax(t *testing.T) {
+	for _, in := range []string{``, `{"ops":[`, `{"ops":[]} {}`} {
+		if _, err := DecodeStrict([]byte(in)); err == nil {
+			t.Errorf("%q: expected an error\n", in)
+		}
+	}
+}
+
+func TestDecodeStrictErrorMessage(t *testing.T) {
+	_, err := DecodeStrict([]byte(`{"ops":[{"insert":"a"},{"retain":-3}]}`))
+	if x := err.Error(); x != "ops[1].retain: op length must be positive" {
+		t.Error("got unexpected message ", x)
+	}
+}
@@ -3,24 +3,36 @@
 package delta
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	//"log"
-	"math"
 	"reflect"
+	"slices"
 )
 
-// Delta is the main type representing a QuillJs delta
+// ErrDiffOnNonDocument is returned by Diff when the receiver has retain or delete ops
+var ErrDiffOnNonDocument = errors.New("diff() called on non-document")
+
+// ErrDiffWithNonDocument is returned by Diff when the other delta has retain or delete ops
+var ErrDiffWithNonDocument = errors.New("diff() called with non-document")
+
+// Delta is the main type representing a QuillJs delta.
+// Compose, Transform, Invert and Slice run on its Seq[Text] form, see Seq
 type Delta struct {
 	Ops []Op `json:"ops"`
 }
 
 // Op is the smallest "operation"
-// TODO: Handle embeds
+// An insert is either text, in Insert, or an embed like an image, in InsertEmbed.
+// A retain is either a number of characters, in Retain, or a change to an embed, in RetainEmbed
 type Op struct {
-	Insert     []rune                 `json:"insert,omitempty"`
-	Retain     *int                   `json:"retain,omitempty"`
-	Attributes map[string]interface{} `json:"attributes,omitempty"`
-	Delete     *int                   `json:"delete,omitempty"`
+	Insert      []rune       `json:"insert,omitempty"`
+	InsertEmbed Embed        `json:"-"`
+	Retain      *int         `json:"retain,omitempty"`
+	RetainEmbed Embed        `json:"-"`
+	Attributes  AttributeMap `json:"attributes,omitempty"`
+	Delete      *int         `json:"delete,omitempty"`
 }
 
 // IsNil tells you if the current Op is a nil operation
@@ -28,16 +40,114 @@ func (o *Op) IsNil() bool {
 	return o.Attributes == nil &&
 		o.Delete == nil &&
 		o.Insert == nil &&
-		o.Retain == nil
+		o.InsertEmbed == nil &&
+		o.Retain == nil &&
+		o.RetainEmbed == nil
+}
+
+// isInsert tells you if the Op inserts either text or an embed
+func (o Op) isInsert() bool {
+	return o.Insert != nil || o.InsertEmbed != nil
 }
 
-// New creates a new Delta with the given ops
+// isRetain tells you if the Op retains either characters or an embed
+func (o Op) isRetain() bool {
+	return o.Retain != nil || o.RetainEmbed != nil
+}
+
+// New creates a new Delta with a copy of the ops slice. The text, embeds and attributes of the ops
+// are shared with you, but the Delta never writes into them
 func New(ops []Op) *Delta {
+	ops = slices.Clone(ops)
+	for i := range ops {
+		// without spare capacity, merging into the text in push has to allocate instead of writing into yours
+		ops[i].Insert = slices.Clip(ops[i].Insert)
+	}
 	return &Delta{
 		Ops: ops,
 	}
 }
 
+// Clone returns a deep copy of the Delta, that shares no slices, maps or pointers with d
+func (d *Delta) Clone() *Delta {
+	if d.Ops == nil {
+		return New(nil)
+	}
+	ops := make([]Op, len(d.Ops))
+	for i, op := range d.Ops {
+		ops[i] = op.clone()
+	}
+	return &Delta{Ops: ops}
+}
+
+// clone returns a deep copy of the Op
+func (o Op) clone() Op {
+	ret := Op{
+		InsertEmbed: cloneValue(o.InsertEmbed).(Embed),
+		RetainEmbed: cloneValue(o.RetainEmbed).(Embed),
+		Attributes:  o.Attributes.clone(),
+	}
+	if o.Insert != nil {
+		ret.Insert = append([]rune{}, o.Insert...)
+	}
+	if o.Retain != nil {
+		n := *o.Retain
+		ret.Retain = &n
+	}
+	if o.Delete != nil {
+		n := *o.Delete
+		ret.Delete = &n
+	}
+	return ret
+}
+
+// Equal tells you if d and other make the same change. Both are compared in their canonical form,
+// where consecutive ops that can be merged are merged, empty ops are dropped, and nil and empty
+// attributes are the same. Trailing retains are kept, use Chop first if they don't matter to you
+func (d *Delta) Equal(other Delta) bool {
+	a, b := d.canonical(), other.canonical()
+	if len(a.Ops) != len(b.Ops) {
+		return false
+	}
+	for i := range a.Ops {
+		if !a.Ops[i].equal(b.Ops[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// canonical returns a copy of d with every op pushed again, so fragmented ops get merged
+func (d *Delta) canonical() *Delta {
+	delta := New(nil)
+	for _, op := range d.Clone().Ops {
+		if op.IsNil() || (op.InsertEmbed == nil && op.RetainEmbed == nil && OpsLength(op) <= 0) {
+			continue
+		}
+		delta.Push(op)
+	}
+	return delta
+}
+
+// equal compares two ops by value
+func (o Op) equal(other Op) bool {
+	return string(o.Insert) == string(other.Insert) &&
+		(o.Insert == nil) == (other.Insert == nil) &&
+		valuesEqual(o.InsertEmbed, other.InsertEmbed) &&
+		intsEqual(o.Retain, other.Retain) &&
+		valuesEqual(o.RetainEmbed, other.RetainEmbed) &&
+		intsEqual(o.Delete, other.Delete) &&
+		o.Attributes.Equal(other.Attributes)
+}
+
+// intsEqual compares the values of two *int, two nil pointers are equal
+func intsEqual(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
 // FromJSON takes a list of ops in json format and creates a Delta
 func FromJSON(in []byte) (*Delta, error) {
 	var ret Delta
@@ -47,11 +157,13 @@ func FromJSON(in []byte) (*Delta, error) {
 	return &ret, nil
 }
 
-// UnmarshalJSON let's us unmarshal a string in the `insert` op to a []rune
+// UnmarshalJSON let's us unmarshal a string in the `insert` op to a []rune, and objects in
+// `insert` and `retain` to an Embed
 func (o *Op) UnmarshalJSON(data []byte) error {
 	type Alias Op
 	aux := &struct {
-		Insert string `json:"insert"`
+		Insert json.RawMessage `json:"insert"`
+		Retain json.RawMessage `json:"retain"`
 		*Alias
 	}{
 		Alias: (*Alias)(o),
@@ -59,25 +171,71 @@ func (o *Op) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
-	o.Insert = []rune(aux.Insert)
+	if retain := bytes.TrimSpace(aux.Retain); len(retain) > 0 && !bytes.Equal(retain, []byte("null")) {
+		if retain[0] == '{' {
+			if err := unmarshalEmbed(retain, &o.RetainEmbed); err != nil {
+				return err
+			}
+		} else {
+			var n int
+			if err := json.Unmarshal(retain, &n); err != nil {
+				return errors.New("retain must be an integer or an object")
+			}
+			o.Retain = &n
+		}
+	}
+	insert := bytes.TrimSpace(aux.Insert)
+	if len(insert) == 0 || bytes.Equal(insert, []byte("null")) {
+		return nil
+	}
+	if insert[0] == '{' {
+		return unmarshalEmbed(insert, &o.InsertEmbed)
+	}
+	var text string
+	if err := json.Unmarshal(insert, &text); err != nil {
+		return errors.New("insert must be a string or an object")
+	}
+	if len(text) > 0 {
+		o.Insert = []rune(text)
+	}
 	return nil
 }
 
-// MarshalJSON let's us marshal our Insert []rune into a string
+// unmarshalEmbed keeps numbers as json.Number so embeds round trip without losing precision
+func unmarshalEmbed(data []byte, embed *Embed) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(embed)
+}
+
+// MarshalJSON let's us marshal our Insert []rune into a string, and embeds as objects
 func (o *Op) MarshalJSON() ([]byte, error) {
 	type Alias Op
+	var insert, retain interface{}
+	if o.InsertEmbed != nil {
+		insert = o.InsertEmbed
+	} else if len(o.Insert) > 0 {
+		insert = string(o.Insert)
+	}
+	if o.RetainEmbed != nil {
+		retain = o.RetainEmbed
+	} else if o.Retain != nil {
+		retain = *o.Retain
+	}
 	return json.Marshal(&struct {
-		Insert string `json:"insert,omitempty"`
+		Insert interface{} `json:"insert,omitempty"`
+		Retain interface{} `json:"retain,omitempty"`
 		*Alias
 	}{
-		Insert: string(o.Insert),
+		Insert: insert,
+		Retain: retain,
 		Alias:  (*Alias)(o),
 	})
 }
 
 // Insert takes a string and a map of attributes and adds them to the Delta d
 // If the string is empty, we return the original delta
-func (d *Delta) Insert(text string, attrs map[string]interface{}) *Delta {
+func (d *Delta) Insert(text string, attrs AttributeMap) *Delta {
 	if len([]rune(text)) == 0 {
 		return d
 	}
@@ -85,7 +243,24 @@ func (d *Delta) Insert(text string, attrs map[string]interface{}) *Delta {
 		Insert: []rune(text),
 	}
 
-	if attrs != nil {
+	if len(attrs) > 0 {
+		newOp.Attributes = attrs
+	}
+	d.Push(newOp)
+	return d
+}
+
+// InsertEmbed adds an embed, like an image or a video, with the given attributes to the Delta d
+// If the embed is nil, we return the original delta
+func (d *Delta) InsertEmbed(embed Embed, attrs AttributeMap) *Delta {
+	if embed == nil {
+		return d
+	}
+	newOp := Op{
+		InsertEmbed: embed,
+	}
+
+	if len(attrs) > 0 {
 		newOp.Attributes = attrs
 	}
 	d.Push(newOp)
@@ -103,14 +278,30 @@ func (d *Delta) Delete(n int) *Delta {
 }
 
 // Retain keeps n characters and applies the attrs if present
-func (d *Delta) Retain(n int, attrs map[string]interface{}) *Delta {
+func (d *Delta) Retain(n int, attrs AttributeMap) *Delta {
 	if n <= 0 {
 		return d
 	}
 	newOp := Op{
 		Retain: &n,
 	}
-	if attrs != nil {
+	if len(attrs) > 0 {
+		newOp.Attributes = attrs
+	}
+	d.Push(newOp)
+
+	return d
+}
+
+// RetainEmbed keeps one embed, applies the change in embed to it, and applies the attrs if present
+func (d *Delta) RetainEmbed(embed Embed, attrs AttributeMap) *Delta {
+	if embed == nil {
+		return d
+	}
+	newOp := Op{
+		RetainEmbed: embed,
+	}
+	if len(attrs) > 0 {
 		newOp.Attributes = attrs
 	}
 	d.Push(newOp)
@@ -118,8 +309,15 @@ func (d *Delta) Retain(n int, attrs map[string]interface{}) *Delta {
 	return d
 }
 
-// Push adds the newOp Operation to the delta, but reorganizes the ops based on certain rules
+// Push adds the newOp Operation to the delta, but reorganizes the ops based on certain rules.
+// It adds a copy of newOp, so changing newOp later doesn't change the Delta, see Builder to avoid the copy
 func (d *Delta) Push(newOp Op) *Delta {
+	return d.push(newOp.clone())
+}
+
+// push adds newOp without copying it. Merging two inserts appends to the text of the last op in place:
+// the Delta owns that text, because it was copied by Push, or has no spare capacity, because New clipped it
+func (d *Delta) push(newOp Op) *Delta {
 	idx := len(d.Ops)
 	var lastOp *Op
 	if idx > 0 {
@@ -141,7 +339,7 @@ func (d *Delta) Push(newOp Op) *Delta {
 
 		// Since it does not matter if we insert before or after deleting at the same index,
 		// always prefer to insert first
-		if lastOp.Delete != nil && newOp.Insert != nil {
+		if lastOp.Delete != nil && newOp.isInsert() {
 			idx--
 			if idx < 1 {
 				d.Ops = append([]Op{newOp}, d.Ops...)
@@ -149,7 +347,7 @@ func (d *Delta) Push(newOp Op) *Delta {
 			}
 			lastOp = &d.Ops[idx-1]
 		}
-		if reflect.DeepEqual(newOp.Attributes, lastOp.Attributes) {
+		if newOp.Attributes.Equal(lastOp.Attributes) {
 			if newOp.Insert != nil && lastOp.Insert != nil {
 				mergedText := append(lastOp.Insert, newOp.Insert...)
 				d.Ops[idx-1] = Op{
@@ -200,96 +398,189 @@ func (d *Delta) Chop() *Delta {
 }
 
 // Compose returns a Delta that is equivalent to applying the operations of own Delta, followed by another Delta.
+// Changes to the same embed are composed by the EmbedHandler registered for it, Compose panics if there is none.
+// It also panics with ErrSurrogateSplit if other splits a surrogate pair of d, see CheckSurrogates
 func (d *Delta) Compose(other Delta) *Delta {
-	thisIter := OpsIterator(d.Ops)
-	otherIter := OpsIterator(other.Ops)
-	delta := New(nil)
-	for thisIter.HasNext() || otherIter.HasNext() {
-		if otherIter.PeekType() == "insert" {
-			delta.Push(otherIter.Next(math.MaxInt64))
-		} else if thisIter.PeekType() == "delete" {
-			delta.Push(thisIter.Next(math.MaxInt64))
-		} else {
-			length := int(math.Min(float64(thisIter.PeekLength()), float64(otherIter.PeekLength())))
-			thisOp := thisIter.Next(length)
-			otherOp := otherIter.Next(length)
-			if otherOp.Retain != nil {
-				newOp := Op{}
-				if thisOp.Retain != nil {
-					newOp.Retain = &length
-				} else {
-					newOp.Insert = append([]rune(nil), thisOp.Insert...)
-				}
-				// Preserve null when composing with a retain, otherwise remove it for inserts
-				attributes := AttrCompose(thisOp.Attributes, otherOp.Attributes, thisOp.Retain != nil)
-				if attributes != nil {
-					newOp.Attributes = attributes
-				}
-				delta.Push(newOp)
-				// Other op should be delete, we could be an insert or retain
-				// Insert + delete cancels out
-			} else if otherOp.Delete != nil && thisOp.Retain != nil {
-				delta.Push(otherOp)
-			}
-		}
-	}
-	return delta.Chop()
+	return FromSeq(*d.seq().Compose(*other.seq()))
 }
 
-// Concat concatenates two Deltas
+// Concat returns a new Delta with the ops of other after the ops of d
 func (d *Delta) Concat(other Delta) *Delta {
-	delta := New(d.Ops)
+	delta := d.Clone()
 	if len(other.Ops) > 0 {
 		delta.Push(other.Ops[0])
-		delta.Ops = append(delta.Ops, other.Ops[1:]...)
+		for _, op := range other.Ops[1:] {
+			delta.Ops = append(delta.Ops, op.clone())
+		}
 	}
 	return delta
 }
 
-// TransformPosition returns the new index after applying a list of Ops
-func (d *Delta) TransformPosition(index int, priority bool) int {
-	thisIter := OpsIterator(d.Ops)
-	offset := 0
-	for thisIter.HasNext() && offset <= index {
-		length := thisIter.PeekLength()
-		nextType := thisIter.PeekType()
-		thisIter.Next(math.MaxInt64)
-		if nextType == "delete" {
-			index -= int(math.Min(float64(length), float64(index-offset)))
-			continue
-		} else if nextType == "insert" && (offset < index || !priority) {
-			index += length
+// Slice returns the ops between start and end, splitting the ops at the edges if needed.
+// Use math.MaxInt64 as end to slice up to the end of the Delta.
+// It panics with ErrSurrogateSplit if start or end falls in the middle of a surrogate pair
+func (d *Delta) Slice(start, end int) *Delta {
+	return FromSeq(*d.seq().Slice(start, end))
+}
+
+// Length returns the sum of the lengths of all the ops in the Delta
+func (d *Delta) Length() int {
+	length := 0
+	for _, op := range d.Ops {
+		length += OpsLength(op)
+	}
+	return length
+}
+
+// ChangeLength returns how much longer (or shorter if negative) a document gets after applying the Delta
+func (d *Delta) ChangeLength() int {
+	length := 0
+	for _, op := range d.Ops {
+		if op.isInsert() {
+			length += OpsLength(op)
+		} else if op.Delete != nil {
+			length -= *op.Delete
 		}
-		offset += length
 	}
-	return index
+	return length
 }
 
-// Transform given Delta against own operations
+// EachLine calls fn for every line of the document d, with the inline ops of the line,
+// the attributes of the newline that ends it and the index of the line.
+// newline defaults to "\n" if empty. Iteration stops at the first op that is not an insert,
+// or when fn returns false
+func (d *Delta) EachLine(fn func(line Delta, attrs map[string]interface{}, index int) bool, newline string) {
+	if newline == "" {
+		newline = "\n"
+	}
+	separator := []rune(newline)
+	iter := OpsIterator(d.Ops)
+	line := New(nil)
+	i := 0
+	for iter.HasNext() {
+		if iter.PeekType() != OpInsert {
+			return
+		}
+		text := iter.peekText()
+		index := runesIndex(text, separator)
+		if index < 0 {
+			line.Push(iter.NextOp())
+		} else if index > 0 {
+			line.Push(iter.Next(textLength(text[:index])))
+		} else {
+			if !fn(*line, iter.Next(textLength(separator)).Attributes, i) {
+				return
+			}
+			i++
+			line = New(nil)
+		}
+	}
+	if line.Length() > 0 {
+		fn(*line, nil, i)
+	}
+}
+
+// runesIndex returns the index of the first instance of sep in s, or -1 if sep is not present in s
+func runesIndex(s, sep []rune) int {
+	for i := 0; i+len(sep) <= len(s); i++ {
+		j := 0
+		for j < len(sep) && s[i+j] == sep[j] {
+			j++
+		}
+		if j == len(sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+// TransformPosition returns the new index after applying a list of Ops.
+// With priority, an insert right at index goes after it, otherwise index moves past the insert
+func (d *Delta) TransformPosition(index int, priority bool) int {
+	return d.TransformPositions([]int{index}, priority)[0]
+}
+
+// Transform given Delta against own operations.
 func (d *Delta) Transform(other Delta, priority bool) *Delta {
+	return FromSeq(*d.seq().Transform(*other.seq(), priority))
+}
+
+// Invert returns a Delta that undoes d when applied on top of the document it was applied to.
+// base is that document, before applying d. Like Compose, it panics if d changes an embed that has no EmbedHandler,
+// or if d splits a surrogate pair of base
+func (d *Delta) Invert(base Delta) *Delta {
+	return FromSeq(*d.seq().Invert(*base.seq()))
+}
+
+// Diff returns a Delta that turns the document d into the document other.
+// Both deltas must only contain inserts, otherwise you get ErrDiffOnNonDocument or ErrDiffWithNonDocument
+func (d *Delta) Diff(other Delta) (*Delta, error) {
+	thisText, ok := documentText(d.Ops)
+	if !ok {
+		return nil, ErrDiffOnNonDocument
+	}
+	otherText, ok := documentText(other.Ops)
+	if !ok {
+		return nil, ErrDiffWithNonDocument
+	}
 	thisIter := OpsIterator(d.Ops)
 	otherIter := OpsIterator(other.Ops)
 	delta := New(nil)
-	for thisIter.HasNext() || otherIter.HasNext() {
-		if thisIter.PeekType() == "insert" && (priority || otherIter.PeekType() != "insert") {
-			delta.Retain(OpsLength(thisIter.Next(math.MaxInt64)), nil)
-		} else if otherIter.PeekType() == "insert" {
-			delta.Push(otherIter.Next(math.MaxInt64))
-		} else {
-			length := int(math.Min(float64(thisIter.PeekLength()), float64(otherIter.PeekLength())))
-			thisOp := thisIter.Next(length)
-			otherOp := otherIter.Next(length)
-			if thisOp.Delete != nil {
-				// Our delete either makes their delete redundant or removes their retain
-				continue
-			} else if otherOp.Delete != nil {
-				delta.Push(otherOp)
-			} else {
-				// We retain either their retain or insert
-				delta.Retain(length, AttrTransform(thisOp.Attributes, otherOp.Attributes, priority))
+	// the diff counts runes, but the iterators need lengths in the current LengthMode
+	thisIndex, otherIndex := 0, 0
+	for _, component := range runesDiff(thisText, otherText) {
+		var length int
+		switch component.kind {
+		case diffInsert:
+			length = textLength(otherText[otherIndex : otherIndex+component.length])
+			otherIndex += component.length
+		case diffDelete:
+			length = textLength(thisText[thisIndex : thisIndex+component.length])
+			thisIndex += component.length
+		case diffEqual:
+			length = textLength(thisText[thisIndex : thisIndex+component.length])
+			thisIndex += component.length
+			otherIndex += component.length
+		}
+		for length > 0 {
+			opLength := 0
+			switch component.kind {
+			case diffInsert:
+				opLength = min(otherIter.PeekLength(), length)
+				delta.Push(otherIter.Next(opLength))
+			case diffDelete:
+				opLength = min(thisIter.PeekLength(), length)
+				thisIter.Next(opLength)
+				delta.Delete(opLength)
+			case diffEqual:
+				opLength = min(thisIter.PeekLength(), otherIter.PeekLength(), length)
+				thisOp := thisIter.Next(opLength)
+				otherOp := otherIter.Next(opLength)
+				if reflect.DeepEqual(thisOp.Insert, otherOp.Insert) && valuesEqual(thisOp.InsertEmbed, otherOp.InsertEmbed) {
+					delta.Retain(opLength, thisOp.Attributes.Diff(otherOp.Attributes))
+				} else {
+					delta.Push(otherOp).Delete(opLength)
+				}
 			}
+			length -= opLength
 		}
 	}
+	return delta.Chop(), nil
+}
 
-	return delta.Chop()
+// documentText joins all the inserts of ops, using a null character for each embed.
+// It returns false if there is any retain or delete
+func documentText(ops []Op) ([]rune, bool) {
+	var text []rune
+	for _, op := range ops {
+		if !op.isInsert() {
+			return nil, false
+		}
+		if op.InsertEmbed != nil {
+			text = append(text, 0)
+		} else {
+			text = append(text, op.Insert...)
+		}
+	}
+	return text, true
 }
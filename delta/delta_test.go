@@ -3,6 +3,7 @@ package delta
 import (
 	"bytes"
 	"encoding/json"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -20,6 +21,85 @@ func TestNoopOps(t *testing.T) {
 		t.Error("failed to create Delta with nil ops")
 	}
 }
+func TestEqualFragmented(t *testing.T) {
+	a := New([]Op{{Insert: []rune("Hel")}, {Insert: []rune("lo")}, {Retain: intPtr(1)}, {Retain: intPtr(2)}})
+	b := New(nil).Insert("Hello", nil).Retain(3, nil)
+	if !a.Equal(*b) || !b.Equal(*a) {
+		t.Errorf("expected %+v to equal %+v\n", a, b)
+	}
+	if len(a.Ops) != 4 {
+		t.Errorf("expected Equal not to change the delta, got %+v\n", a)
+	}
+}
+func TestEqualNilAndEmptyAttributes(t *testing.T) {
+	a := New([]Op{{Insert: []rune("A"), Attributes: AttributeMap{}}, {Delete: intPtr(1)}})
+	b := New(nil).Insert("A", nil).Delete(1)
+	if !a.Equal(*b) {
+		t.Errorf("expected %+v to equal %+v\n", a, b)
+	}
+}
+func TestEqualJSON(t *testing.T) {
+	a, err := FromJSON([]byte(`{"ops":[{"insert":"A","attributes":{"size":15}},{"insert":{"image":"a.png"}},{"retain":0},{"delete":2}]}`))
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	b := New(nil).Insert("A", AttributeMap{"size": 15}).InsertEmbed(Embed{"image": "a.png"}, nil).Delete(2)
+	if !a.Equal(*b) {
+		t.Errorf("expected %+v to equal %+v\n", a, b)
+	}
+}
+func TestEqualDifferent(t *testing.T) {
+	cases := [][2]*Delta{
+		{New(nil).Insert("A", nil), New(nil).Insert("B", nil)},
+		{New(nil).Insert("A", nil), New(nil).Insert("A", AttributeMap{"bold": true})},
+		{New(nil).Retain(1, nil), New(nil).Retain(2, nil)},
+		{New(nil).Retain(1, nil), New(nil).Delete(1)},
+		{New(nil).Retain(1, nil), New(nil)},
+		{New(nil).Insert(string(rune(0)), nil), New(nil).InsertEmbed(Embed{"image": "a.png"}, nil)},
+		{New(nil).InsertEmbed(Embed{"image": "a.png"}, nil), New(nil).InsertEmbed(Embed{"image": "b.png"}, nil)},
+	}
+	for _, c := range cases {
+		if c[0].Equal(*c[1]) || c[1].Equal(*c[0]) {
+			t.Errorf("expected %+v not to equal %+v\n", c[0], c[1])
+		}
+	}
+}
+func TestClone(t *testing.T) {
+	font := map[string]interface{}{"family": "Helvetica"}
+	a := New(nil).
+		Insert("Hello", AttributeMap{"font": font}).
+		InsertEmbed(Embed{"image": "a.png"}, nil).
+		Retain(2, nil).
+		Delete(1)
+	b := a.Clone()
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("expected %+v but got %+v\n", a, b)
+	}
+	b.Ops[0].Insert[0] = 'J'
+	b.Ops[0].Attributes["font"].(map[string]interface{})["family"] = "Arial"
+	b.Ops[1].InsertEmbed["image"] = "b.png"
+	*b.Ops[2].Retain = 5
+	*b.Ops[3].Delete = 5
+	b.Push(Op{Delete: intPtr(1)})
+
+	expected := New(nil).
+		Insert("Hello", AttributeMap{"font": map[string]interface{}{"family": "Helvetica"}}).
+		InsertEmbed(Embed{"image": "a.png"}, nil).
+		Retain(2, nil).
+		Delete(1)
+	if !reflect.DeepEqual(expected, a) {
+		t.Errorf("expected %+v but got %+v\n", expected, a)
+	}
+	if New(nil).Clone().Ops != nil {
+		t.Error("expected the clone of an empty delta to have nil ops")
+	}
+}
+
+// intPtr returns a pointer to n, to build ops by hand
+func intPtr(n int) *int {
+	return &n
+}
+
 func TestInsert1(t *testing.T) {
 	n := New(nil)
 	n.Insert("test", nil)
@@ -53,6 +133,16 @@ func TestInsertWithAttr(t *testing.T) {
 	}
 }
 
+func TestInsertWithEmptyAttr(t *testing.T) {
+	n := New(nil).Insert("test", AttributeMap{}).Retain(1, AttributeMap{})
+	if n.Ops[0].Attributes != nil {
+		t.Errorf("expected nil attributes, got: %+v\n", n.Ops)
+	}
+	if n.Chop(); len(n.Ops) != 1 {
+		t.Errorf("expected the retain to be chopped, got: %+v\n", n.Ops)
+	}
+}
+
 func TestInsertAfterDelete(t *testing.T) {
 	n := New(nil)
 	n.Delete(1).Insert("a", nil)
@@ -231,6 +321,56 @@ func TestPushMultiInsertNonMathingAttrs(t *testing.T) {
 	}
 }
 
+func TestInsertEmbed(t *testing.T) {
+	n := New(nil)
+	attr := make(map[string]interface{})
+	attr["alt"] = "Quill"
+	n.InsertEmbed(Embed{"image": "http://quilljs.com"}, attr)
+	if len(n.Ops) != 1 {
+		t.Errorf("failed to create Delta with embed, got: %+v\n", n.Ops)
+	}
+	if n.Ops[0].InsertEmbed["image"] != "http://quilljs.com" {
+		t.Errorf("failed to create Delta with embed, got: %+v\n", n.Ops)
+	}
+	if n.Ops[0].Attributes["alt"] != "Quill" {
+		t.Errorf("failed to create Delta with embed and attr, got: %+v\n", n.Ops)
+	}
+	if n.InsertEmbed(nil, nil); len(n.Ops) != 1 {
+		t.Errorf("expected nil embed to be a noop, got: %+v\n", n.Ops)
+	}
+}
+func TestPushEmbedsDontMerge(t *testing.T) {
+	n := New(nil)
+	embed := Embed{"image": "http://quilljs.com"}
+	n.InsertEmbed(embed, nil).InsertEmbed(embed, nil).Insert("a", nil)
+	if len(n.Ops) != 3 {
+		t.Errorf("expected 3 ops, got: %+v\n", n.Ops)
+	}
+}
+func TestPushEmbedAfterDelete(t *testing.T) {
+	n := New(nil)
+	embed := Embed{"image": "http://quilljs.com"}
+	n.Retain(1, nil).Delete(1).InsertEmbed(embed, nil)
+	if len(n.Ops) != 3 {
+		t.Errorf("expected 3 ops, got: %+v\n", n.Ops)
+	}
+	if n.Ops[1].InsertEmbed == nil || n.Ops[2].Delete == nil {
+		t.Errorf("expected the embed before the delete, got: %+v\n", n.Ops)
+	}
+}
+
+func TestPushMultiInsertMatchingObjectAttrs(t *testing.T) {
+	n := New(nil)
+	n.Insert("Diego ", AttributeMap{"font": map[string]interface{}{"size": float64(15)}})
+	n.Insert("Smith", AttributeMap{"font": map[string]interface{}{"size": 15}})
+	if len(n.Ops) != 1 {
+		t.Errorf("failed to Push multi insert with object attributes, got: %+v\n", n.Ops)
+	}
+	if string(n.Ops[0].Insert) != "Diego Smith" {
+		t.Errorf("failed to Push to Delta, got: %+v\n", string(n.Ops[0].Insert))
+	}
+}
+
 func TestPushMultiRetainMathingAttrs(t *testing.T) {
 	n := New(nil)
 	attr := make(map[string]interface{})
@@ -503,6 +643,58 @@ func TestDeltaComposeImmutability(t *testing.T) {
 	}
 }
 
+func TestDeltaComposeEmbedRetain(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["width"] = "300"
+	embed := Embed{"image": "http://quilljs.com"}
+	a := New(nil).InsertEmbed(embed, nil)
+	b := New(nil).Retain(1, attr)
+	expected := New(nil).InsertEmbed(embed, attr)
+	x := a.Compose(*b)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestDeltaComposeEmbedDelete(t *testing.T) {
+	embed := Embed{"image": "http://quilljs.com"}
+	a := New(nil).Insert("A", nil).InsertEmbed(embed, nil).Insert("B", nil)
+	b := New(nil).Retain(1, nil).Delete(1)
+	expected := New(nil).Insert("AB", nil)
+	x := a.Compose(*b)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestDeltaComposeEmbedInText(t *testing.T) {
+	embed := Embed{"formula": "e=mc^2"}
+	a := New(nil).Insert("Hello", nil)
+	b := New(nil).Retain(2, nil).InsertEmbed(embed, nil)
+	expected := New(nil).Insert("He", nil).InsertEmbed(embed, nil).Insert("llo", nil)
+	x := a.Compose(*b)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestDeltaComposeDoesNotModifyAttributes(t *testing.T) {
+	bold := AttributeMap{"bold": true}
+	italic := AttributeMap{"italic": true}
+	a := New(nil).Insert("123", nil).Insert("4", bold)
+	b := New(nil).Retain(4, italic)
+
+	x := a.Compose(*b)
+	expected := New(nil).Insert("123", AttributeMap{"italic": true}).Insert("4", AttributeMap{"bold": true, "italic": true})
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if !reflect.DeepEqual(AttributeMap{"bold": true}, bold) {
+		t.Errorf("expected bold to be unchanged but got %+v\n", bold)
+	}
+	if !reflect.DeepEqual(AttributeMap{"italic": true}, italic) {
+		t.Errorf("expected italic to be unchanged but got %+v\n", italic)
+	}
+}
+
 func TestChop(t *testing.T) {
 	x := New(nil).Insert("a", nil).Insert("b", nil).Insert("c", nil).Retain(1, nil)
 	ret := x.Chop()
@@ -569,6 +761,234 @@ func TestConcatMergable(t *testing.T) {
 	}
 }
 
+func TestSliceStart(t *testing.T) {
+	x := New(nil).Retain(2, nil).Insert("A", nil).Slice(2, math.MaxInt64)
+	expected := New(nil).Insert("A", nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestSliceStartAndEndChop(t *testing.T) {
+	x := New(nil).Insert("0123456789", nil).Slice(2, 7)
+	expected := New(nil).Insert("23456", nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestSliceStartAndEndMultipleChop(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	x := New(nil).Insert("0123", attr).Insert("4567", nil).Slice(3, 5)
+	expected := New(nil).Insert("3", attr).Insert("4", nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestSliceStartAndEnd(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	x := New(nil).Retain(2, nil).Insert("A", attr).Insert("B", nil).Slice(2, 3)
+	expected := New(nil).Insert("A", attr)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestSliceAll(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	delta := New(nil).Retain(2, nil).Insert("A", attr).Insert("B", nil)
+	x := delta.Slice(0, math.MaxInt64)
+	if !reflect.DeepEqual(delta, x) {
+		t.Errorf("expected %+v but got %+v\n", delta, x)
+	}
+}
+func TestSliceSplitOps(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	x := New(nil).Insert("AB", attr).Insert("C", nil).Slice(1, 2)
+	expected := New(nil).Insert("B", attr)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestSliceSplitOpsMultipleTimes(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	x := New(nil).Insert("ABC", attr).Insert("D", nil).Slice(1, 2)
+	expected := New(nil).Insert("B", attr)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestSliceRetainWithAttributes(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	x := New(nil).Retain(5, attr).Delete(2).Slice(3, 6)
+	expected := New(nil).Retain(2, attr).Delete(1)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestSliceChinese(t *testing.T) {
+	x := New(nil).Insert("你好，世界!", nil).Slice(3, 5)
+	if string(x.Ops[0].Insert) != "世界" {
+		t.Errorf("expected '世界' but got %+v\n", string(x.Ops[0].Insert))
+	}
+}
+
+func TestLengthDocument(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	delta := New(nil).Insert("AB", attr).Insert("你好", nil)
+	if x := delta.Length(); x != 4 {
+		t.Error("expected 4 but got ", x)
+	}
+}
+func TestLengthMixed(t *testing.T) {
+	delta := New(nil).Insert("AB", nil).Retain(2, nil).Delete(1)
+	if x := delta.Length(); x != 5 {
+		t.Error("expected 5 but got ", x)
+	}
+}
+func TestChangeLengthMixed(t *testing.T) {
+	delta := New(nil).Insert("AB", nil).Retain(2, nil).Delete(1)
+	if x := delta.ChangeLength(); x != 1 {
+		t.Error("expected 1 but got ", x)
+	}
+}
+func TestChangeLengthDelete(t *testing.T) {
+	delta := New(nil).Retain(2, map[string]interface{}{"bold": true}).Delete(3)
+	if x := delta.ChangeLength(); x != -3 {
+		t.Error("expected -3 but got ", x)
+	}
+}
+
+func TestEachLine(t *testing.T) {
+	bold := make(map[string]interface{})
+	bold["bold"] = true
+	align := make(map[string]interface{})
+	align["align"] = "right"
+	delta := New(nil).
+		Insert("Hello\n\n", nil).
+		Insert("World", bold).
+		Insert(" 你好", nil).
+		Insert("\n", align).
+		Insert("!", nil)
+
+	expectedLines := []*Delta{
+		New(nil).Insert("Hello", nil),
+		New(nil),
+		New(nil).Insert("World", bold).Insert(" 你好", nil),
+		New(nil).Insert("!", nil),
+	}
+	expectedAttrs := []map[string]interface{}{nil, nil, align, nil}
+	calls := 0
+	delta.EachLine(func(line Delta, attrs map[string]interface{}, index int) bool {
+		if index != calls {
+			t.Errorf("expected index %d but got %d\n", calls, index)
+		}
+		if !reflect.DeepEqual(*expectedLines[index], line) {
+			t.Errorf("expected line %+v but got %+v\n", expectedLines[index], line)
+		}
+		if !reflect.DeepEqual(expectedAttrs[index], attrs) {
+			t.Errorf("expected attrs %+v but got %+v\n", expectedAttrs[index], attrs)
+		}
+		calls++
+		return true
+	}, "\n")
+	if calls != 4 {
+		t.Error("expected 4 calls but got ", calls)
+	}
+}
+func TestEachLineEmbed(t *testing.T) {
+	align := make(map[string]interface{})
+	align["align"] = "right"
+	embed := Embed{"image": "http://quilljs.com"}
+	delta := New(nil).Insert("World", nil).InsertEmbed(embed, nil).Insert("\n", align).Insert("!", nil)
+
+	expected := New(nil).Insert("World", nil).InsertEmbed(embed, nil)
+	calls := 0
+	delta.EachLine(func(line Delta, attrs map[string]interface{}, index int) bool {
+		if index == 0 && !reflect.DeepEqual(*expected, line) {
+			t.Errorf("expected line %+v but got %+v\n", expected, line)
+		}
+		if index == 0 && !reflect.DeepEqual(align, attrs) {
+			t.Errorf("expected attrs %+v but got %+v\n", align, attrs)
+		}
+		calls++
+		return true
+	}, "\n")
+	if calls != 2 {
+		t.Error("expected 2 calls but got ", calls)
+	}
+}
+
+func TestEachLineAcrossOps(t *testing.T) {
+	bold := make(map[string]interface{})
+	bold["bold"] = true
+	delta := New(nil).Insert("Hel", nil).Insert("lo\nWor", bold).Insert("ld\n", nil)
+
+	expectedLines := []*Delta{
+		New(nil).Insert("Hel", nil).Insert("lo", bold),
+		New(nil).Insert("Wor", bold).Insert("ld", nil),
+	}
+	calls := 0
+	delta.EachLine(func(line Delta, attrs map[string]interface{}, index int) bool {
+		if !reflect.DeepEqual(*expectedLines[index], line) {
+			t.Errorf("expected line %+v but got %+v\n", expectedLines[index], line)
+		}
+		calls++
+		return true
+	}, "")
+	if calls != 2 {
+		t.Error("expected 2 calls but got ", calls)
+	}
+}
+func TestEachLineTrailingNewline(t *testing.T) {
+	delta := New(nil).Insert("Hello\nWorld!\n", nil)
+	calls := 0
+	delta.EachLine(func(line Delta, attrs map[string]interface{}, index int) bool {
+		calls++
+		return true
+	}, "\n")
+	if calls != 2 {
+		t.Error("expected 2 calls but got ", calls)
+	}
+}
+func TestEachLineNonDocument(t *testing.T) {
+	delta := New(nil).Retain(1, nil).Delete(2)
+	calls := 0
+	delta.EachLine(func(line Delta, attrs map[string]interface{}, index int) bool {
+		calls++
+		return true
+	}, "\n")
+	if calls != 0 {
+		t.Error("expected 0 calls but got ", calls)
+	}
+}
+func TestEachLineEarlyReturn(t *testing.T) {
+	delta := New(nil).Insert("Hello\nNew\nWorld!", nil)
+	calls := 0
+	delta.EachLine(func(line Delta, attrs map[string]interface{}, index int) bool {
+		calls++
+		return index != 1
+	}, "\n")
+	if calls != 2 {
+		t.Error("expected 2 calls but got ", calls)
+	}
+}
+func TestEachLineCustomNewline(t *testing.T) {
+	delta := New(nil).Insert("a|b", nil).Insert("|c", nil)
+	var lines []string
+	delta.EachLine(func(line Delta, attrs map[string]interface{}, index int) bool {
+		lines = append(lines, string(line.Ops[0].Insert))
+		return true
+	}, "|")
+	if !reflect.DeepEqual([]string{"a", "b", "c"}, lines) {
+		t.Errorf("expected [a b c] but got %+v\n", lines)
+	}
+}
+
 func TestTransformPositionInsertBeforePos(t *testing.T) {
 	delta := New(nil).Insert("A", nil)
 	if x := delta.TransformPosition(2, false); x != 3 {
@@ -806,6 +1226,22 @@ func TestTransformRetainRetainNoPrio(t *testing.T) {
 		t.Errorf("expected '1' op but got %+v\n", x)
 	}
 }
+func TestTransformEmbedInsert(t *testing.T) {
+	embed := Embed{"image": "http://quilljs.com"}
+	a := New(nil).InsertEmbed(embed, nil)
+	b := New(nil).Insert("B", nil)
+	x := a.Transform(*b, true)
+	expected := New(nil).Retain(1, nil).Insert("B", nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	x = b.Transform(*a, false)
+	expected = New(nil).InsertEmbed(embed, nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
 func TestTransformAlternatingEdits(t *testing.T) {
 
 	a := New(nil).Retain(2, nil).Insert("si", nil).Delete(5)
@@ -1031,6 +1467,46 @@ func TestMarshalJSON4(t *testing.T) {
 	}
 }
 
+func TestMarshalJSONEmbed(t *testing.T) {
+	in := []byte(`{"ops":[{"insert":"Hi "},{"insert":{"image":"http://quilljs.com/logo.png"},"attributes":{"width":"120"}},{"insert":{"formula":{"expr":"e=mc^2","size":12345678901234567890}}},{"insert":"\\n"}]}`)
+	d, err := FromJSON(in)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if len(d.Ops) != 4 {
+		t.Fatalf("expected 4 ops but got %+v\n", d.Ops)
+	}
+	if d.Ops[1].InsertEmbed["image"] != "http://quilljs.com/logo.png" {
+		t.Errorf("expected image embed but got %+v\n", d.Ops[1])
+	}
+	if d.Ops[1].Insert != nil {
+		t.Errorf("expected nil text insert but got %+v\n", d.Ops[1])
+	}
+	out, err := json.Marshal(d)
+	if err != nil {
+		t.Error("failed to get json string, err: ", err)
+	}
+	if bytes.Compare(in, out) != 0 {
+		t.Errorf("expected:\n'%+v' but got :\n'%+v'\n", string(in[:]), string(out[:]))
+	}
+}
+func TestUnmarshalJSONInvalidInsert(t *testing.T) {
+	in := []byte(`{"ops":[{"insert":5}]}`)
+	if _, err := FromJSON(in); err == nil {
+		t.Error("expected an error for a numeric insert")
+	}
+}
+func TestUnmarshalJSONRetainHasNoInsert(t *testing.T) {
+	in := []byte(`{"ops":[{"retain":5}]}`)
+	d, err := FromJSON(in)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if d.Ops[0].Insert != nil || d.Ops[0].InsertEmbed != nil {
+		t.Errorf("expected no insert but got %+v\n", d.Ops[0])
+	}
+}
+
 func BenchmarkFromJson1(t *testing.B) {
 	in := []byte(`{"ops":[{"retain":35},{"retain":11,"attributes":{"bold":true}}]}`)
 	var delta *Delta
@@ -1073,3 +1549,313 @@ func TestDeleteChinese2Char(t *testing.T) {
 		t.Errorf("Expected: '%s' but got %+v\n", expected, string(ret.Ops[0].Insert))
 	}
 }
+
+func TestInvertInsert(t *testing.T) {
+	delta := New(nil).Retain(2, nil).Insert("A", nil)
+	base := New(nil).Insert("123456", nil)
+	expected := New(nil).Retain(2, nil).Delete(1)
+	inverted := delta.Invert(*base)
+	if !reflect.DeepEqual(expected, inverted) {
+		t.Errorf("expected %+v but got %+v\n", expected, inverted)
+	}
+	if x := base.Compose(*delta).Compose(*inverted); !reflect.DeepEqual(base, x) {
+		t.Errorf("expected %+v but got %+v\n", base, x)
+	}
+}
+func TestInvertDelete(t *testing.T) {
+	delta := New(nil).Retain(2, nil).Delete(3)
+	base := New(nil).Insert("123456", nil)
+	expected := New(nil).Retain(2, nil).Insert("345", nil)
+	inverted := delta.Invert(*base)
+	if !reflect.DeepEqual(expected, inverted) {
+		t.Errorf("expected %+v but got %+v\n", expected, inverted)
+	}
+	if x := base.Compose(*delta).Compose(*inverted); !reflect.DeepEqual(base, x) {
+		t.Errorf("expected %+v but got %+v\n", base, x)
+	}
+}
+func TestInvertRetain(t *testing.T) {
+	attr1 := make(map[string]interface{})
+	attr1["bold"] = true
+	attr2 := make(map[string]interface{})
+	attr2["bold"] = nil
+	delta := New(nil).Retain(2, nil).Retain(3, attr1)
+	base := New(nil).Insert("123456", nil)
+	expected := New(nil).Retain(2, nil).Retain(3, attr2)
+	inverted := delta.Invert(*base)
+	if !reflect.DeepEqual(expected, inverted) {
+		t.Errorf("expected %+v but got %+v\n", expected, inverted)
+	}
+	if x := base.Compose(*delta).Compose(*inverted); !reflect.DeepEqual(base, x) {
+		t.Errorf("expected %+v but got %+v\n", base, x)
+	}
+}
+func TestInvertRetainDifferentAttributes(t *testing.T) {
+	attr1 := make(map[string]interface{})
+	attr1["bold"] = true
+	attr2 := make(map[string]interface{})
+	attr2["italic"] = true
+	attr3 := make(map[string]interface{})
+	attr3["italic"] = nil
+	base := New(nil).Insert("123", nil).Insert("4", attr1)
+	delta := New(nil).Retain(4, attr2)
+	expected := New(nil).Retain(4, attr3)
+	inverted := delta.Invert(*base)
+	if !reflect.DeepEqual(expected, inverted) {
+		t.Errorf("expected %+v but got %+v\n", expected, inverted)
+	}
+	if x := base.Compose(*delta).Compose(*inverted); !reflect.DeepEqual(base, x) {
+		t.Errorf("expected %+v but got %+v\n", base, x)
+	}
+}
+func TestInvertCombined(t *testing.T) {
+	delta := New(nil).
+		Retain(2, nil).
+		Delete(2).
+		Insert("AB", map[string]interface{}{"italic": true}).
+		Retain(2, map[string]interface{}{"italic": nil, "bold": true}).
+		Retain(2, map[string]interface{}{"color": "red"}).
+		Delete(1)
+	base := New(nil).
+		Insert("123", map[string]interface{}{"bold": true}).
+		Insert("456", map[string]interface{}{"italic": true}).
+		Insert("789", map[string]interface{}{"color": "red", "bold": true})
+	expected := New(nil).
+		Retain(2, nil).
+		Insert("3", map[string]interface{}{"bold": true}).
+		Insert("4", map[string]interface{}{"italic": true}).
+		Delete(2).
+		Retain(2, map[string]interface{}{"italic": true, "bold": nil}).
+		Retain(2, nil).
+		Insert("9", map[string]interface{}{"color": "red", "bold": true})
+	inverted := delta.Invert(*base)
+	if !reflect.DeepEqual(expected, inverted) {
+		t.Errorf("expected %+v but got %+v\n", expected, inverted)
+	}
+	if x := base.Compose(*delta).Compose(*inverted); !reflect.DeepEqual(base, x) {
+		t.Errorf("expected %+v but got %+v\n", base, x)
+	}
+}
+
+func TestDiffInsert(t *testing.T) {
+	a := New(nil).Insert("A", nil)
+	b := New(nil).Insert("AB", nil)
+	expected := New(nil).Retain(1, nil).Insert("B", nil)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestDiffDelete(t *testing.T) {
+	a := New(nil).Insert("AB", nil)
+	b := New(nil).Insert("A", nil)
+	expected := New(nil).Retain(1, nil).Delete(1)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestDiffRetain(t *testing.T) {
+	a := New(nil).Insert("A", nil)
+	b := New(nil).Insert("A", nil)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if len(x.Ops) != 0 {
+		t.Errorf("expected '0' ops but got %+v\n", x)
+	}
+}
+func TestDiffFormat(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	a := New(nil).Insert("A", nil)
+	b := New(nil).Insert("A", attr)
+	expected := New(nil).Retain(1, attr)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestDiffInconvenientIndexes(t *testing.T) {
+	attr1 := make(map[string]interface{})
+	attr1["bold"] = true
+	attr2 := make(map[string]interface{})
+	attr2["italic"] = true
+	attr3 := make(map[string]interface{})
+	attr3["color"] = "red"
+	a := New(nil).Insert("12", attr1).Insert("34", attr2)
+	b := New(nil).Insert("123", attr3)
+
+	exp1 := make(map[string]interface{})
+	exp1["bold"] = nil
+	exp1["color"] = "red"
+	exp2 := make(map[string]interface{})
+	exp2["italic"] = nil
+	exp2["color"] = "red"
+	expected := New(nil).Retain(2, exp1).Retain(1, exp2).Delete(1)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestDiffCombination(t *testing.T) {
+	attr1 := make(map[string]interface{})
+	attr1["color"] = "red"
+	attr2 := make(map[string]interface{})
+	attr2["color"] = "blue"
+	attr3 := make(map[string]interface{})
+	attr3["bold"] = true
+	attr4 := make(map[string]interface{})
+	attr4["italic"] = true
+	a := New(nil).Insert("Bad", attr1).Insert("cat", attr2)
+	b := New(nil).Insert("Good", attr3).Insert("dog", attr4)
+
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if ret := a.Compose(*x); !reflect.DeepEqual(b, ret) {
+		t.Errorf("expected %+v but got %+v\ndiff was %+v\n", b, ret, x)
+	}
+}
+func TestDiffChinese(t *testing.T) {
+	a := New(nil).Insert("你好，世界!", nil)
+	b := New(nil).Insert("你好，朋友!", nil)
+	expected := New(nil).Retain(3, nil).Insert("朋友", nil).Delete(2)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestDiffImmutability(t *testing.T) {
+	attr1 := make(map[string]interface{})
+	attr1["color"] = "red"
+	attr2 := make(map[string]interface{})
+	attr2["color"] = "red"
+	a1 := New(nil).Insert("A", attr1)
+	a2 := New(nil).Insert("A", attr1)
+	b1 := New(nil).Insert("A", map[string]interface{}{"bold": true}).Insert("B", nil)
+	b2 := New(nil).Insert("A", map[string]interface{}{"bold": true}).Insert("B", nil)
+
+	if _, err := a1.Diff(*b1); err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(a1, a2) {
+		t.Errorf("expected %+v but got %+v\n", a2, a1)
+	}
+	if !reflect.DeepEqual(b1, b2) {
+		t.Errorf("expected %+v but got %+v\n", b2, b1)
+	}
+	if !reflect.DeepEqual(attr1, attr2) {
+		t.Errorf("expected %+v but got %+v\n", attr2, attr1)
+	}
+}
+func TestDiffEmbedMatch(t *testing.T) {
+	a := New(nil).InsertEmbed(Embed{"image": "http://quilljs.com"}, nil)
+	b := New(nil).InsertEmbed(Embed{"image": "http://quilljs.com"}, nil)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if len(x.Ops) != 0 {
+		t.Errorf("expected '0' ops but got %+v\n", x)
+	}
+}
+func TestDiffEmbedMismatch(t *testing.T) {
+	a := New(nil).InsertEmbed(Embed{"image": "http://quilljs.com", "alt": "Overwrite"}, nil)
+	b := New(nil).InsertEmbed(Embed{"image": "http://quilljs.com"}, nil)
+	expected := New(nil).InsertEmbed(Embed{"image": "http://quilljs.com"}, nil).Delete(1)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestDiffEmbedFalsePositive(t *testing.T) {
+	a := New(nil).InsertEmbed(Embed{"image": "http://quilljs.com"}, nil)
+	b := New(nil).Insert(string(rune(0)), nil)
+	expected := New(nil).Insert(string(rune(0)), nil).Delete(1)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestDiffObjectAttributes(t *testing.T) {
+	a := New(nil).Insert("A", AttributeMap{"font": map[string]interface{}{"family": "Helvetica", "size": "15px"}})
+	b := New(nil).Insert("A", AttributeMap{"font": map[string]interface{}{"family": "Helvetica", "size": "15px"}})
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if len(x.Ops) != 0 {
+		t.Errorf("expected '0' ops but got %+v\n", x)
+	}
+	c := New(nil).Insert("A", AttributeMap{"font": map[string]interface{}{"family": "Arial", "size": "15px"}})
+	expected := New(nil).Retain(1, AttributeMap{"font": map[string]interface{}{"family": "Arial", "size": "15px"}})
+	x, err = a.Diff(*c)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestDiffEmbedJSONNumbers(t *testing.T) {
+	a, err := FromJSON([]byte(`{"ops":[{"insert":{"video":{"id":1}}}]}`))
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	b := New(nil).InsertEmbed(Embed{"video": map[string]interface{}{"id": 1}}, nil)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if len(x.Ops) != 0 {
+		t.Errorf("expected '0' ops but got %+v\n", x)
+	}
+}
+
+func TestDiffNonDocument(t *testing.T) {
+	a := New(nil).Insert("A", nil)
+	b := New(nil).Retain(1, nil).Insert("B", nil)
+
+	if _, err := a.Diff(*b); err != ErrDiffWithNonDocument {
+		t.Errorf("expected ErrDiffWithNonDocument but got %+v\n", err)
+	}
+	if _, err := b.Diff(*a); err != ErrDiffOnNonDocument {
+		t.Errorf("expected ErrDiffOnNonDocument but got %+v\n", err)
+	}
+}
+
+// BenchmarkInsertMerge builds a long insert one character at a time,
+// every Insert merges into the previous one so it must not copy the text built so far
+func BenchmarkInsertMerge(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		d := New(nil)
+		for j := 0; j < 40000; j++ {
+			d.Insert("a", nil)
+		}
+	}
+}
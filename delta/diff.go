@@ -0,0 +1,146 @@
+package delta
+
+// diffKind tells you if a diffComponent keeps, adds or removes characters
+type diffKind int
+
+const (
+	diffEqual diffKind = iota
+	diffInsert
+	diffDelete
+)
+
+// diffComponent is a run of characters that share the same diffKind
+type diffComponent struct {
+	kind   diffKind
+	length int
+}
+
+// diffMaxSteps is how far myersBisect looks from each end before it gives up, so texts that have
+// been rewritten, with tens of thousands of edits, don't take forever to diff
+const diffMaxSteps = 4096
+
+// runesDiff returns the shortest list of components that turn a into b.
+// It runs Myers' O(ND) algorithm in linear space, splitting the texts at the middle snake
+// and diffing both halves, like diff-match-patch and Quill's fast-diff do.
+// When a part of a and b needs more than about 2*diffMaxSteps edits, that part is deleted and
+// inserted whole, so the diff is still right but not the shortest
+func runesDiff(a, b []rune) []diffComponent {
+	return appendRunesDiff(nil, a, b)
+}
+
+// appendRunesDiff adds the components that turn a into b.
+// It trims the common prefix and suffix, then bisects what's left
+func appendRunesDiff(components []diffComponent, a, b []rune) []diffComponent {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	components = appendDiffComponent(components, diffEqual, prefix)
+	if x, y, ok := myersBisect(a, b); ok {
+		components = appendRunesDiff(components, a[:x], b[:y])
+		components = appendRunesDiff(components, a[x:], b[y:])
+	} else {
+		components = appendDiffComponent(components, diffDelete, len(a))
+		components = appendDiffComponent(components, diffInsert, len(b))
+	}
+	return appendDiffComponent(components, diffEqual, suffix)
+}
+
+// myersBisect finds where a shortest edit path from a to b crosses the middle snake, walking
+// forward from the start and backward from the end of the texts until the two paths overlap.
+// It only keeps the furthest reaching x on each diagonal k, so memory grows with the size of the
+// input. It returns false if a and b have nothing in common, if one of them is empty, or if the
+// paths don't meet within diffMaxSteps
+func myersBisect(a, b []rune) (int, int, bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := min((n+m+1)/2, diffMaxSteps)
+	offset := maxD
+	// one more slot on each side so k-1 and k+1 are always in range
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+	delta := n - m
+	// with an odd delta the forward path is the one that can overlap the backward one
+	front := delta%2 != 0
+	// diagonals that went past the end of a or b, no need to look at them again
+	kStart, kEnd, k2Start, k2End := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + kStart; k <= d-kEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				kEnd += 2
+			case y > m:
+				kStart += 2
+			case front:
+				if k2 := offset + delta - k; k2 >= 0 && k2 < len(backward) && backward[k2] != -1 && x >= n-backward[k2] {
+					return x, y, true
+				}
+			}
+		}
+
+		for k := -d + k2Start; k <= d-k2End; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				k2End += 2
+			case y > m:
+				k2Start += 2
+			case !front:
+				if k1 := offset + delta - k; k1 >= 0 && k1 < len(forward) && forward[k1] != -1 && forward[k1] >= n-x {
+					return forward[k1], forward[k1] - (k1 - offset), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// appendDiffComponent adds length characters of the given kind, merging them with the last component if possible
+func appendDiffComponent(components []diffComponent, kind diffKind, length int) []diffComponent {
+	if length <= 0 {
+		return components
+	}
+	if last := len(components) - 1; last >= 0 && components[last].kind == kind {
+		components[last].length += length
+		return components
+	}
+	return append(components, diffComponent{kind: kind, length: length})
+}
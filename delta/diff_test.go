@@ -0,0 +1,196 @@
+package delta
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestRunesDiffEqual(t *testing.T) {
+	x := runesDiff([]rune("abc"), []rune("abc"))
+	expected := []diffComponent{{kind: diffEqual, length: 3}}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestRunesDiffEmpty(t *testing.T) {
+	if x := runesDiff(nil, nil); len(x) != 0 {
+		t.Errorf("expected no components but got %+v\n", x)
+	}
+	x := runesDiff(nil, []rune("ab"))
+	expected := []diffComponent{{kind: diffInsert, length: 2}}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	x = runesDiff([]rune("ab"), nil)
+	expected = []diffComponent{{kind: diffDelete, length: 2}}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+func TestRunesDiffMiddle(t *testing.T) {
+	x := runesDiff([]rune("abXcd"), []rune("abYYcd"))
+	expected := []diffComponent{
+		{kind: diffEqual, length: 2},
+		{kind: diffDelete, length: 1},
+		{kind: diffInsert, length: 2},
+		{kind: diffEqual, length: 2},
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+// applyDiff rebuilds b from a and the components to make sure they are consistent
+func applyDiff(a, b []rune, components []diffComponent) []rune {
+	var ret []rune
+	ai, bi := 0, 0
+	for _, c := range components {
+		switch c.kind {
+		case diffEqual:
+			ret = append(ret, a[ai:ai+c.length]...)
+			ai += c.length
+			bi += c.length
+		case diffInsert:
+			ret = append(ret, b[bi:bi+c.length]...)
+			bi += c.length
+		case diffDelete:
+			ai += c.length
+		}
+	}
+	return ret
+}
+
+func TestRunesDiffShortest(t *testing.T) {
+	cases := [][2]string{
+		{"ABCABBA", "CBABAC"},
+		{"kitten", "sitting"},
+		{"Badcat", "Gooddog"},
+		{"the quick brown fox", "a quick brown dog"},
+		{"你好，世界!", "世界，你好!"},
+	}
+	// edit distances (inserts + deletes) for the cases above
+	distances := []int{5, 5, 11, 8, 6}
+	for i, c := range cases {
+		a, b := []rune(c[0]), []rune(c[1])
+		x := runesDiff(a, b)
+		if ret := string(applyDiff(a, b, x)); ret != c[1] {
+			t.Errorf("expected '%s' but got '%s'\n", c[1], ret)
+		}
+		d := 0
+		for _, component := range x {
+			if component.kind != diffEqual {
+				d += component.length
+			}
+		}
+		if d != distances[i] {
+			t.Errorf("expected distance %d for %+v but got %d\n", distances[i], c, d)
+		}
+	}
+}
+
+// lcsDistance is the edit distance (inserts + deletes) between a and b, from the longest common subsequence
+func lcsDistance(a, b []rune) int {
+	row := make([]int, len(b)+1)
+	for i := range a {
+		prev := 0
+		for j := range b {
+			next := row[j+1]
+			if a[i] == b[j] {
+				row[j+1] = prev + 1
+			} else {
+				row[j+1] = max(row[j+1], row[j])
+			}
+			prev = next
+		}
+	}
+	return len(a) + len(b) - 2*row[len(b)]
+}
+
+func TestRunesDiffRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	text := func() []rune {
+		ret := make([]rune, r.Intn(40))
+		for i := range ret {
+			ret[i] = rune('a' + r.Intn(4))
+		}
+		return ret
+	}
+	for i := 0; i < 2000; i++ {
+		a, b := text(), text()
+		x := runesDiff(a, b)
+		if ret := string(applyDiff(a, b, x)); ret != string(b) {
+			t.Fatalf("expected '%s' but got '%s'\n", string(b), ret)
+		}
+		d := 0
+		for _, component := range x {
+			if component.kind != diffEqual {
+				d += component.length
+			}
+		}
+		if expected := lcsDistance(a, b); d != expected {
+			t.Fatalf("expected distance %d for '%s' and '%s' but got %d\n", expected, string(a), string(b), d)
+		}
+	}
+}
+
+func TestRunesDiffRewrittenDocument(t *testing.T) {
+	// a saved document rewritten from scratch, the diff must not keep the whole search in memory
+	r := rand.New(rand.NewSource(1))
+	a, b := randomText(r, 100000), randomText(r, 100000)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	x := runesDiff(a, b)
+	runtime.ReadMemStats(&after)
+	if ret := string(applyDiff(a, b, x)); ret != string(b) {
+		t.Error("expected the diff to rebuild the rewritten document")
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("expected less than 64MB to be allocated but got %dMB\n", allocated>>20)
+	}
+}
+
+func TestRunesDiffLargeDocumentEdits(t *testing.T) {
+	// scattered edits in a large document still give the shortest diff
+	r := rand.New(rand.NewSource(1))
+	a := randomText(r, 50000)
+	var b []rune
+	for i, c := range a {
+		if i%500 == 0 {
+			b = append(b, '0')
+		}
+		b = append(b, c)
+	}
+	x := runesDiff(a, b)
+	if ret := string(applyDiff(a, b, x)); ret != string(b) {
+		t.Error("expected the diff to rebuild the edited document")
+	}
+	d := 0
+	for _, component := range x {
+		if component.kind != diffEqual {
+			d += component.length
+		}
+	}
+	if d != 100 {
+		t.Errorf("expected distance 100 but got %d\n", d)
+	}
+}
+
+// randomText returns n random lowercase letters
+func randomText(r *rand.Rand, n int) []rune {
+	ret := make([]rune, n)
+	for i := range ret {
+		ret[i] = rune('a' + r.Intn(26))
+	}
+	return ret
+}
+
+func BenchmarkRunesDiffRewrittenDocument(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	x, y := randomText(r, 10000), randomText(r, 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runesDiff(x, y)
+	}
+}
@@ -0,0 +1,103 @@
+package delta
+
+// Document is an insert-only Delta, the contents of a Quill editor, with the APIs of the editor
+// to read it and to change it. It always ends with a newline, and indexes and lengths use the
+// current LengthMode, like in Quill.
+// Methods given an index or a length that ends in the middle of a surrogate pair panic with ErrSurrogateSplit
+type Document struct {
+	delta Delta
+}
+
+// NewDocument returns a Document with a copy of d, or an error if d is not a valid document,
+// see Delta.Validate. A newline is added at the end of d if it doesn't have one
+func NewDocument(d Delta) (*Document, error) {
+	if err := d.Validate(ValidateOptions{Document: true}); err != nil {
+		return nil, err
+	}
+	return &Document{delta: *withNewline(d)}, nil
+}
+
+// withNewline returns a copy of d that ends with a newline, adding one if needed
+func withNewline(d Delta) *Delta {
+	delta := d.Clone()
+	if n := len(d.Ops); n > 0 {
+		if text := d.Ops[n-1].Insert; d.Ops[n-1].InsertEmbed == nil && len(text) > 0 && text[len(text)-1] == '\n' {
+			return delta
+		}
+	}
+	return delta.Insert("\n", nil)
+}
+
+// Delta returns a copy of the contents of the Document
+func (doc *Document) Delta() *Delta {
+	return doc.delta.Clone()
+}
+
+// GetLength returns the length of the Document, embeds count as 1
+func (doc *Document) GetLength() int {
+	return doc.delta.Length()
+}
+
+// GetContents returns the contents between index and index+length
+func (doc *Document) GetContents(index, length int) *Delta {
+	return doc.delta.Slice(index, index+length)
+}
+
+// GetText returns the text between index and index+length, embeds are left out
+func (doc *Document) GetText(index, length int) string {
+	var text []rune
+	for _, op := range doc.delta.Slice(index, index+length).Ops {
+		text = append(text, op.Insert...)
+	}
+	return string(text)
+}
+
+// GetFormat returns the formats shared by everything between index and index+length:
+// the inline formats of the text and embeds, and the block formats of the lines they are in.
+// A format with different values in the range is left out.
+// With a length of 0 you get the formats at the cursor, which are the ones of the character before it
+func (doc *Document) GetFormat(index, length int) AttributeMap {
+	var inline, block AttributeMap
+	inlineFound, blockFound := false, false
+	combine := func(formats *AttributeMap, found *bool, attrs AttributeMap) {
+		if !*found {
+			*formats, *found = attrs.clone(), true
+			return
+		}
+		for k, v := range *formats {
+			if !valuesEqual(v, attrs[k]) {
+				delete(*formats, k)
+			}
+		}
+	}
+
+	start, end := index, index+length
+	lineStart := 0
+	doc.delta.EachLine(func(line Delta, attrs map[string]interface{}, _ int) bool {
+		lineLength := line.Length()
+		lineEnd := lineStart + lineLength
+		defer func() {
+			lineStart = lineEnd + 1
+		}()
+		if lineEnd < start {
+			return true
+		}
+		if length > 0 && lineStart >= end {
+			return false
+		}
+		combine(&block, &blockFound, attrs)
+
+		from, to := max(start-lineStart, 0), min(end-lineStart, lineLength)
+		if length == 0 {
+			// the character before the cursor, or the first one of the line
+			from, to = max(from-1, 0), min(max(from, 1), lineLength)
+		}
+		for _, op := range line.Slice(from, to).Ops {
+			combine(&inline, &inlineFound, op.Attributes)
+		}
+		return length > 0
+	}, "\n")
+
+	// like Quill, inline formats win over block formats with the same name
+	return block.Compose(inline, false)
+}
@@ -0,0 +1,134 @@
+package delta
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testDocument(t *testing.T) *Document {
+	t.Helper()
+	d := New(nil).
+		Insert("Hello ", map[string]interface{}{"bold": true}).
+		Insert("World", map[string]interface{}{"bold": true, "italic": true}).
+		Insert("\n", map[string]interface{}{"header": 1}).
+		InsertEmbed(Embed{"image": "a.png"}, map[string]interface{}{"width": "10"}).
+		Insert("one\n", map[string]interface{}{"list": "bullet"}).
+		Insert("two", map[string]interface{}{"color": "red"}).
+		Insert("\n", map[string]interface{}{"list": "bullet"})
+	doc, err := NewDocument(*d)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	return doc
+}
+
+func TestNewDocument(t *testing.T) {
+	_, err := NewDocument(*New(nil).Insert("a", nil).Retain(1, nil))
+	if !errors.Is(err, ErrNotADocument) {
+		t.Errorf("expected ErrNotADocument but got %+v\n", err)
+	}
+
+	d := New(nil).Insert("a\n", nil)
+	doc, err := NewDocument(*d)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	d.Ops[0].Insert[0] = 'b'
+	if x := doc.GetText(0, 2); x != "a\n" {
+		t.Error("expected the document to keep its own copy but got ", x)
+	}
+
+	// like Quill, a document always ends with a newline
+	for _, c := range []struct {
+		d, expected *Delta
+	}{
+		{New(nil), New(nil).Insert("\n", nil)},
+		{New(nil).Insert("Hello", nil), New(nil).Insert("Hello\n", nil)},
+		{New(nil).InsertEmbed(Embed{"image": "a.png"}, nil), New(nil).InsertEmbed(Embed{"image": "a.png"}, nil).Insert("\n", nil)},
+		{New(nil).Insert("a\n", AttributeMap{"header": 1}), New(nil).Insert("a\n", AttributeMap{"header": 1})},
+	} {
+		doc, err := NewDocument(*c.d)
+		if err != nil {
+			t.Fatal("failed with ", err)
+		}
+		if x := doc.Delta(); !reflect.DeepEqual(c.expected, x) {
+			t.Errorf("expected %+v but got %+v\n", c.expected, x)
+		}
+	}
+}
+
+func TestDocumentGetLength(t *testing.T) {
+	if x := testDocument(t).GetLength(); x != 21 {
+		t.Error("expected 21 but got ", x)
+	}
+}
+
+func TestDocumentGetText(t *testing.T) {
+	doc := testDocument(t)
+	if x := doc.GetText(0, doc.GetLength()); x != "Hello World\none\ntwo\n" {
+		t.Errorf("expected the whole text but got %q\n", x)
+	}
+	if x := doc.GetText(9, 6); x != "ld\non" {
+		t.Errorf("expected \"ld\\non\" but got %q\n", x)
+	}
+	if x := doc.GetText(30, 5); x != "" {
+		t.Errorf("expected no text but got %q\n", x)
+	}
+}
+
+func TestDocumentGetContents(t *testing.T) {
+	doc := testDocument(t)
+	expected := New(nil).
+		Insert("\n", map[string]interface{}{"header": 1}).
+		InsertEmbed(Embed{"image": "a.png"}, map[string]interface{}{"width": "10"}).
+		Insert("o", map[string]interface{}{"list": "bullet"})
+	if x := doc.GetContents(11, 3); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestDocumentGetFormat(t *testing.T) {
+	doc := testDocument(t)
+	cases := []struct {
+		index, length int
+		expected      AttributeMap
+	}{
+		{0, 5, AttributeMap{"bold": true, "header": 1}},
+		{3, 5, AttributeMap{"bold": true, "header": 1}},
+		{6, 5, AttributeMap{"bold": true, "italic": true, "header": 1}},
+		{6, 6, AttributeMap{"bold": true, "italic": true, "header": 1}},
+		{6, 7, nil},
+		{12, 1, AttributeMap{"width": "10", "list": "bullet"}},
+		{13, 6, AttributeMap{"list": "bullet"}},
+		{16, 3, AttributeMap{"color": "red", "list": "bullet"}},
+		{30, 1, nil},
+	}
+	for _, c := range cases {
+		if x := doc.GetFormat(c.index, c.length); !reflect.DeepEqual(c.expected, x) {
+			t.Errorf("GetFormat(%d, %d): expected %+v but got %+v\n", c.index, c.length, c.expected, x)
+		}
+	}
+}
+
+func TestDocumentGetFormatCursor(t *testing.T) {
+	doc := testDocument(t)
+	cases := []struct {
+		index    int
+		expected AttributeMap
+	}{
+		{0, AttributeMap{"bold": true, "header": 1}},
+		{6, AttributeMap{"bold": true, "header": 1}},
+		{7, AttributeMap{"bold": true, "italic": true, "header": 1}},
+		{11, AttributeMap{"bold": true, "italic": true, "header": 1}},
+		{12, AttributeMap{"width": "10", "list": "bullet"}},
+		{16, AttributeMap{"list": "bullet"}},
+		{17, AttributeMap{"color": "red", "list": "bullet"}},
+		{21, nil},
+	}
+	for _, c := range cases {
+		if x := doc.GetFormat(c.index, 0); !reflect.DeepEqual(c.expected, x) {
+			t.Errorf("GetFormat(%d, 0): expected %+v but got %+v\n", c.index, c.expected, x)
+		}
+	}
+}
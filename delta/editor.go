@@ -0,0 +1,95 @@
+package delta
+
+// The methods in this file change the Document the way Quill's editor does, and return the change
+// they applied, ready to be sent to other clients. index and length are clamped to the Document,
+// before its final newline, which Quill never lets an edit remove or format
+
+// InsertText inserts text with the given formats at index
+func (doc *Document) InsertText(index int, text string, formats AttributeMap) *Delta {
+	index, _ = doc.clamp(index, 0)
+	return doc.apply(New(nil).Retain(index, nil).Insert(text, formats))
+}
+
+// InsertEmbed inserts embed with the given formats at index
+func (doc *Document) InsertEmbed(index int, embed Embed, formats AttributeMap) *Delta {
+	index, _ = doc.clamp(index, 0)
+	return doc.apply(New(nil).Retain(index, nil).InsertEmbed(embed, formats))
+}
+
+// DeleteText removes length characters starting at index
+func (doc *Document) DeleteText(index, length int) *Delta {
+	index, length = doc.clamp(index, length)
+	return doc.apply(New(nil).Retain(index, nil).Delete(length))
+}
+
+// FormatText applies formats to everything between index and index+length, a nil value removes a format
+func (doc *Document) FormatText(index, length int, formats AttributeMap) *Delta {
+	index, length = doc.clamp(index, length)
+	return doc.apply(New(nil).Retain(index, nil).Retain(length, formats))
+}
+
+// FormatLine applies formats to the newlines that end the lines between index and index+length,
+// so only block formats like headers and lists change. A length of 0 formats the line at index
+func (doc *Document) FormatLine(index, length int, formats AttributeMap) *Delta {
+	index, length = doc.clamp(index, length)
+	change := New(nil)
+	offset := 0
+	for _, newline := range doc.newlines(index, length) {
+		change.Retain(newline-offset, nil).Retain(1, formats)
+		offset = newline + 1
+	}
+	return doc.apply(change)
+}
+
+// RemoveFormat removes the inline formats between index and index+length,
+// and the block formats of the lines they are in
+func (doc *Document) RemoveFormat(index, length int) *Delta {
+	index, length = doc.clamp(index, length)
+	change := New(nil).Retain(index, nil)
+	for _, op := range doc.delta.Slice(index, index+length).Ops {
+		change.Retain(OpsLength(op), op.Attributes.Diff(nil))
+	}
+	offset := index + length
+	for _, newline := range doc.newlines(index, length) {
+		if newline < offset {
+			continue
+		}
+		// the newline that ends the last line is after the range
+		attrs := doc.delta.Slice(newline, newline+1).Ops[0].Attributes
+		change.Retain(newline-offset, nil).Retain(1, attrs.Diff(nil))
+	}
+	return doc.apply(change)
+}
+
+// SetContents replaces the whole Document with contents, which must be a valid document.
+// Like NewDocument, it adds a newline at the end of contents if it doesn't have one
+func (doc *Document) SetContents(contents Delta) (*Delta, error) {
+	if err := contents.Validate(ValidateOptions{Document: true}); err != nil {
+		return nil, err
+	}
+	return doc.apply(withNewline(contents).Delete(doc.GetLength())), nil
+}
+
+// apply composes change on top of the Document and returns it
+func (doc *Document) apply(change *Delta) *Delta {
+	change = change.Chop()
+	doc.delta = *doc.delta.Compose(*change)
+	return change
+}
+
+// clamp returns index and length moved inside the Document, before its final newline
+func (doc *Document) clamp(index, length int) (int, int) {
+	last := doc.GetLength() - 1
+	index = min(max(index, 0), last)
+	return index, min(max(length, 0), last-index)
+}
+
+// newlines returns the positions of the newlines that end the lines between index and index+length,
+// or the line at index if length is 0
+func (doc *Document) newlines(index, length int) []int {
+	var newlines []int
+	for _, line := range doc.LineRange(index, length) {
+		newlines = append(newlines, line.End())
+	}
+	return newlines
+}
@@ -0,0 +1,150 @@
+package delta
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestDocument(t *testing.T, d *Delta) *Document {
+	t.Helper()
+	doc, err := NewDocument(*d)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	return doc
+}
+
+func expectChange(t *testing.T, doc *Document, change, expectedChange, expectedDoc *Delta) {
+	t.Helper()
+	if !reflect.DeepEqual(expectedChange, change) {
+		t.Errorf("expected change %+v but got %+v\n", expectedChange, change)
+	}
+	if x := doc.Delta(); !expectedDoc.Equal(*x) {
+		t.Errorf("expected document %+v but got %+v\n", expectedDoc, x)
+	}
+}
+
+func TestDocumentInsertText(t *testing.T) {
+	doc := newTestDocument(t, New(nil).Insert("Hello\n", nil))
+	bold := AttributeMap{"bold": true}
+	change := doc.InsertText(5, " World", bold)
+	expectChange(t, doc, change,
+		New(nil).Retain(5, nil).Insert(" World", bold),
+		New(nil).Insert("Hello", nil).Insert(" World", bold).Insert("\n", nil))
+
+	// past the end inserts before the final newline
+	change = doc.InsertText(100, "!", nil)
+	expectChange(t, doc, change,
+		New(nil).Retain(11, nil).Insert("!", nil),
+		New(nil).Insert("Hello", nil).Insert(" World", bold).Insert("!\n", nil))
+}
+
+func TestDocumentInsertEmbed(t *testing.T) {
+	doc := newTestDocument(t, New(nil).Insert("ab\n", nil))
+	image := Embed{"image": "a.png"}
+	change := doc.InsertEmbed(1, image, AttributeMap{"width": "10"})
+	expectChange(t, doc, change,
+		New(nil).Retain(1, nil).InsertEmbed(image, AttributeMap{"width": "10"}),
+		New(nil).Insert("a", nil).InsertEmbed(image, AttributeMap{"width": "10"}).Insert("b\n", nil))
+}
+
+func TestDocumentDeleteText(t *testing.T) {
+	doc := newTestDocument(t, New(nil).Insert("Hello World\n", nil))
+	change := doc.DeleteText(5, 100)
+	expectChange(t, doc, change,
+		New(nil).Retain(5, nil).Delete(6),
+		New(nil).Insert("Hello\n", nil))
+
+	// the final newline can't be deleted, so the line can still be formatted
+	change = doc.DeleteText(0, 100)
+	expectChange(t, doc, change,
+		New(nil).Delete(5),
+		New(nil).Insert("\n", nil))
+	change = doc.FormatLine(0, 0, AttributeMap{"header": 1})
+	expectChange(t, doc, change,
+		New(nil).Retain(1, AttributeMap{"header": 1}),
+		New(nil).Insert("\n", AttributeMap{"header": 1}))
+}
+
+func TestDocumentFormatText(t *testing.T) {
+	doc := newTestDocument(t, New(nil).Insert("Hello", AttributeMap{"italic": true}).Insert(" World\n", nil))
+	change := doc.FormatText(3, 5, AttributeMap{"bold": true, "italic": nil})
+	expectChange(t, doc, change,
+		New(nil).Retain(3, nil).Retain(5, AttributeMap{"bold": true, "italic": nil}),
+		New(nil).Insert("Hel", AttributeMap{"italic": true}).Insert("lo Wo", AttributeMap{"bold": true}).Insert("rld\n", nil))
+}
+
+func TestDocumentFormatLine(t *testing.T) {
+	doc := newTestDocument(t, New(nil).Insert("one\ntwo\nthree\n", nil))
+	list := AttributeMap{"list": "bullet"}
+	change := doc.FormatLine(2, 4, list)
+	expectChange(t, doc, change,
+		New(nil).Retain(3, nil).Retain(1, list).Retain(3, nil).Retain(1, list),
+		New(nil).Insert("one", nil).Insert("\n", list).Insert("two", nil).Insert("\n", list).Insert("three\n", nil))
+
+	// a cursor formats its line
+	change = doc.FormatLine(10, 0, AttributeMap{"header": 1})
+	expectChange(t, doc, change,
+		New(nil).Retain(13, nil).Retain(1, AttributeMap{"header": 1}),
+		New(nil).Insert("one", nil).Insert("\n", list).Insert("two", nil).Insert("\n", list).Insert("three", nil).Insert("\n", AttributeMap{"header": 1}))
+}
+
+func TestDocumentRemoveFormat(t *testing.T) {
+	bold := AttributeMap{"bold": true}
+	list := AttributeMap{"list": "bullet"}
+	image := Embed{"image": "a.png"}
+	doc := newTestDocument(t, New(nil).
+		Insert("one", bold).Insert("\n", list).
+		Insert("two", bold).InsertEmbed(image, AttributeMap{"width": "10"}).Insert("\n", list).
+		Insert("three\n", list))
+	change := doc.RemoveFormat(1, 5)
+	expectChange(t, doc, change,
+		New(nil).Retain(1, nil).
+			Retain(2, AttributeMap{"bold": nil}).Retain(1, AttributeMap{"list": nil}).Retain(2, AttributeMap{"bold": nil}).
+			Retain(2, nil).Retain(1, AttributeMap{"list": nil}),
+		New(nil).Insert("o", bold).Insert("ne\ntw", nil).Insert("o", bold).InsertEmbed(image, AttributeMap{"width": "10"}).Insert("\n", nil).Insert("three\n", list))
+
+	change = doc.RemoveFormat(6, 2)
+	expectChange(t, doc, change,
+		New(nil).Retain(6, nil).Retain(1, AttributeMap{"bold": nil}).Retain(1, AttributeMap{"width": nil}),
+		New(nil).Insert("o", bold).Insert("ne\ntwo", nil).InsertEmbed(image, nil).Insert("\n", nil).Insert("three\n", list))
+}
+
+func TestDocumentSetContents(t *testing.T) {
+	doc := newTestDocument(t, New(nil).Insert("Hello\n", nil))
+	contents := New(nil).Insert("Bye", AttributeMap{"bold": true}).Insert("\n", nil)
+	change, err := doc.SetContents(*contents)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	expectChange(t, doc, change, New(nil).Insert("Bye", AttributeMap{"bold": true}).Insert("\n", nil).Delete(6), contents)
+
+	// contents without a final newline get one
+	change, err = doc.SetContents(*New(nil).Insert("Hi", nil))
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	expectChange(t, doc, change, New(nil).Insert("Hi\n", nil).Delete(4), New(nil).Insert("Hi\n", nil))
+
+	if _, err := doc.SetContents(*New(nil).Retain(1, nil)); err == nil {
+		t.Error("expected an error for contents that are not a document")
+	}
+}
+
+func TestDocumentChangesApply(t *testing.T) {
+	// every change turns the document before it into the document after it
+	doc := newTestDocument(t, New(nil).Insert("Hello World\nsecond line\n", nil))
+	for _, edit := range []func() *Delta{
+		func() *Delta { return doc.InsertText(3, "abc", AttributeMap{"bold": true}) },
+		func() *Delta { return doc.FormatLine(0, 20, AttributeMap{"align": "center"}) },
+		func() *Delta { return doc.DeleteText(2, 4) },
+		func() *Delta { return doc.RemoveFormat(0, 8) },
+		func() *Delta { return doc.FormatText(4, 10, AttributeMap{"italic": true}) },
+	} {
+		before := doc.Delta()
+		change := edit()
+		if x := before.Compose(*change); !x.Equal(*doc.Delta()) {
+			t.Errorf("expected %+v but got %+v\n", doc.Delta(), x)
+		}
+	}
+}
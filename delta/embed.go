@@ -0,0 +1,124 @@
+package delta
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrNoEmbedHandler is used when Compose or Invert find a retain embed with no registered EmbedHandler
+var ErrNoEmbedHandler = errors.New("no handlers for embed type")
+
+// ErrEmbedTypeMismatch is used when a retain embed is applied on top of an embed of a different type
+var ErrEmbedTypeMismatch = errors.New("embed types not matched")
+
+// ErrCannotRetainText is used when a retain embed is applied on top of text
+var ErrCannotRetainText = errors.New("cannot retain text with an embed")
+
+// Embed is a non text insert, like {"image": "https://..."} or {"formula": "e=mc^2"}.
+// It always has a length of 1 and is never merged with or split like text.
+// When used in a retain, it holds a change to the embed it retains, like a table-embed delta
+type Embed map[string]interface{}
+
+// Type returns the type of the embed, which is its key
+func (e Embed) Type() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	return keys[0]
+}
+
+// EmbedHandler knows how to compose, transform and invert changes to one type of embed.
+// a and b are the values of the embeds, the part after the embed type key
+type EmbedHandler interface {
+	Compose(a, b interface{}, keepNil bool) interface{}
+	Transform(a, b interface{}, priority bool) interface{}
+	Invert(a, b interface{}) interface{}
+}
+
+var embedHandlers = struct {
+	sync.RWMutex
+	m map[string]EmbedHandler
+}{m: make(map[string]EmbedHandler)}
+
+// RegisterEmbed makes handler responsible for the embeds of type embedType, replacing any previous handler
+func RegisterEmbed(embedType string, handler EmbedHandler) {
+	embedHandlers.Lock()
+	defer embedHandlers.Unlock()
+	embedHandlers.m[embedType] = handler
+}
+
+// UnregisterEmbed removes the handler for embedType
+func UnregisterEmbed(embedType string) {
+	embedHandlers.Lock()
+	defer embedHandlers.Unlock()
+	delete(embedHandlers.m, embedType)
+}
+
+// getEmbedHandler returns the handler registered for embedType, if any
+func getEmbedHandler(embedType string) (EmbedHandler, bool) {
+	embedHandlers.RLock()
+	defer embedHandlers.RUnlock()
+	handler, ok := embedHandlers.m[embedType]
+	return handler, ok
+}
+
+// embedTypeAndData checks that a and b are embeds of the same type and returns the type and both values
+func embedTypeAndData(a, b Embed) (string, interface{}, interface{}, error) {
+	if a == nil || b == nil {
+		return "", nil, nil, ErrCannotRetainText
+	}
+	embedType := a.Type()
+	if embedType == "" || embedType != b.Type() {
+		return "", nil, nil, fmt.Errorf("%w: %q != %q", ErrEmbedTypeMismatch, embedType, b.Type())
+	}
+	return embedType, a[embedType], b[embedType], nil
+}
+
+// embedHandlerFor is like embedTypeAndData but also finds the registered handler for the embed type
+func embedHandlerFor(a, b Embed) (string, interface{}, interface{}, EmbedHandler, error) {
+	embedType, aData, bData, err := embedTypeAndData(a, b)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+	handler, ok := getEmbedHandler(embedType)
+	if !ok {
+		return "", nil, nil, nil, fmt.Errorf("%w %q", ErrNoEmbedHandler, embedType)
+	}
+	return embedType, aData, bData, handler, nil
+}
+
+// composeEmbed applies the retain embed b on top of the embed a.
+// Like quill, it panics if there is no handler for them or if they are not the same type of embed
+func composeEmbed(a, b Embed, keepNil bool) Embed {
+	embedType, aData, bData, handler, err := embedHandlerFor(a, b)
+	if err != nil {
+		panic(err)
+	}
+	return Embed{embedType: handler.Compose(aData, bData, keepNil)}
+}
+
+// transformEmbed transforms the retain embed b against a, it returns false if they can't be transformed
+func transformEmbed(a, b Embed, priority bool) (Embed, bool) {
+	embedType, aData, bData, handler, err := embedHandlerFor(a, b)
+	if err != nil {
+		return nil, false
+	}
+	return Embed{embedType: handler.Transform(aData, bData, priority)}, true
+}
+
+// invertEmbed returns the retain embed that undoes a on top of the base embed b.
+// Like quill, it panics if there is no handler for them or if they are not the same type of embed
+func invertEmbed(a, b Embed) Embed {
+	embedType, aData, bData, handler, err := embedHandlerFor(a, b)
+	if err != nil {
+		panic(err)
+	}
+	return Embed{embedType: handler.Invert(aData, bData)}
+}
@@ -0,0 +1,259 @@
+package delta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// deltaEmbed is a test EmbedHandler for embeds that hold a whole delta, like {"delta": [ops...]}
+type deltaEmbed struct{}
+
+func deltaFromEmbedData(data interface{}) *Delta {
+	in, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	var ops []Op
+	if err := json.Unmarshal(in, &ops); err != nil {
+		panic(err)
+	}
+	return New(ops)
+}
+
+func (deltaEmbed) Compose(a, b interface{}, keepNil bool) interface{} {
+	return deltaFromEmbedData(a).Compose(*deltaFromEmbedData(b)).Ops
+}
+
+func (deltaEmbed) Transform(a, b interface{}, priority bool) interface{} {
+	return deltaFromEmbedData(a).Transform(*deltaFromEmbedData(b), priority).Ops
+}
+
+func (deltaEmbed) Invert(a, b interface{}) interface{} {
+	return deltaFromEmbedData(a).Invert(*deltaFromEmbedData(b)).Ops
+}
+
+func TestEmbedType(t *testing.T) {
+	if x := (Embed{"image": "http://quilljs.com"}).Type(); x != "image" {
+		t.Errorf("expected 'image' but got '%s'\n", x)
+	}
+	if x := (Embed{}).Type(); x != "" {
+		t.Errorf("expected '' but got '%s'\n", x)
+	}
+}
+
+func TestRetainEmbedJSON(t *testing.T) {
+	in := []byte(`{"ops":[{"retain":3},{"retain":{"delta":[{"insert":"a"}]},"attributes":{"bold":true}}]}`)
+	d, err := FromJSON(in)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if *d.Ops[0].Retain != 3 || d.Ops[0].RetainEmbed != nil {
+		t.Errorf("expected 'retain 3' but got %+v\n", d.Ops[0])
+	}
+	if d.Ops[1].Retain != nil || d.Ops[1].RetainEmbed.Type() != "delta" {
+		t.Errorf("expected a delta retain embed but got %+v\n", d.Ops[1])
+	}
+	if x := OpsLength(d.Ops[1]); x != 1 {
+		t.Error("expected length 1 but got ", x)
+	}
+	out, err := json.Marshal(d)
+	if err != nil {
+		t.Error("failed to get json string, err: ", err)
+	}
+	if bytes.Compare(in, out) != 0 {
+		t.Errorf("expected:\n'%+v' but got :\n'%+v'\n", string(in[:]), string(out[:]))
+	}
+}
+
+func TestRetainEmbedJSONInvalid(t *testing.T) {
+	in := []byte(`{"ops":[{"retain":"3"}]}`)
+	if _, err := FromJSON(in); err == nil {
+		t.Error("expected an error for a string retain")
+	}
+}
+
+func TestRetainEmbedPushAndChop(t *testing.T) {
+	embed := Embed{"delta": []Op{{Insert: []rune("a")}}}
+	n := New(nil).Retain(1, nil).RetainEmbed(embed, nil).RetainEmbed(embed, nil)
+	if len(n.Ops) != 3 {
+		t.Errorf("expected retain embeds not to merge, got: %+v\n", n.Ops)
+	}
+	if n.Chop(); len(n.Ops) != 3 {
+		t.Errorf("expected chop to keep the retain embed, got: %+v\n", n.Ops)
+	}
+	if n.RetainEmbed(nil, nil); len(n.Ops) != 3 {
+		t.Errorf("expected nil retain embed to be a noop, got: %+v\n", n.Ops)
+	}
+}
+
+func TestComposeInsertEmbedRetainEmbed(t *testing.T) {
+	RegisterEmbed("delta", deltaEmbed{})
+	defer UnregisterEmbed("delta")
+
+	a := New(nil).InsertEmbed(Embed{"delta": []Op{{Insert: []rune("a")}}}, nil)
+	b := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("b", nil).Ops}, nil)
+	x := a.Compose(*b)
+	expected := New(nil).InsertEmbed(Embed{"delta": New(nil).Insert("ba", nil).Ops}, nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestComposeRetainEmbedRetainEmbed(t *testing.T) {
+	RegisterEmbed("delta", deltaEmbed{})
+	defer UnregisterEmbed("delta")
+
+	a := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("a", nil).Ops}, nil)
+	b := New(nil).RetainEmbed(Embed{"delta": New(nil).Retain(1, nil).Insert("b", nil).Ops}, nil)
+	x := a.Compose(*b)
+	expected := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("ab", nil).Ops}, nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestComposeRetainRetainEmbed(t *testing.T) {
+	embed := Embed{"delta": New(nil).Insert("b", nil).Ops}
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+
+	a := New(nil).Retain(2, attr)
+	b := New(nil).Retain(1, nil).RetainEmbed(embed, nil)
+	x := a.Compose(*b)
+	expected := New(nil).Retain(1, attr).RetainEmbed(embed, attr)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+
+	x = b.Compose(*New(nil).Retain(2, attr))
+	expected = New(nil).Retain(1, attr).RetainEmbed(embed, attr)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestComposeRetainEmbedDelete(t *testing.T) {
+	a := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("a", nil).Ops}, nil)
+	b := New(nil).Delete(1)
+	x := a.Compose(*b)
+	expected := New(nil).Delete(1)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestComposeRetainEmbedNoHandler(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrNoEmbedHandler) {
+			t.Errorf("expected ErrNoEmbedHandler but got %+v\n", err)
+		}
+	}()
+	a := New(nil).InsertEmbed(Embed{"delta": New(nil).Insert("a", nil).Ops}, nil)
+	b := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("b", nil).Ops}, nil)
+	a.Compose(*b)
+}
+
+func TestComposeRetainEmbedMismatch(t *testing.T) {
+	RegisterEmbed("delta", deltaEmbed{})
+	defer UnregisterEmbed("delta")
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrEmbedTypeMismatch) {
+			t.Errorf("expected ErrEmbedTypeMismatch but got %+v\n", err)
+		}
+	}()
+	a := New(nil).InsertEmbed(Embed{"image": "http://quilljs.com"}, nil)
+	b := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("b", nil).Ops}, nil)
+	a.Compose(*b)
+}
+
+func TestComposeRetainEmbedOnText(t *testing.T) {
+	RegisterEmbed("delta", deltaEmbed{})
+	defer UnregisterEmbed("delta")
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrCannotRetainText) {
+			t.Errorf("expected ErrCannotRetainText but got %+v\n", err)
+		}
+	}()
+	a := New(nil).Insert("a", nil)
+	b := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("b", nil).Ops}, nil)
+	a.Compose(*b)
+}
+
+func TestTransformRetainEmbed(t *testing.T) {
+	RegisterEmbed("delta", deltaEmbed{})
+	defer UnregisterEmbed("delta")
+
+	a := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("a", nil).Ops}, nil)
+	b := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("b", nil).Ops}, nil)
+
+	x := a.Transform(*b, true)
+	expected := New(nil).RetainEmbed(Embed{"delta": New(nil).Retain(1, nil).Insert("b", nil).Ops}, nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	x = a.Transform(*b, false)
+	expected = New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("b", nil).Ops}, nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestTransformRetainEmbedNoHandler(t *testing.T) {
+	a := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("a", nil).Ops}, nil)
+	b := New(nil).RetainEmbed(Embed{"delta": New(nil).Insert("b", nil).Ops}, nil)
+
+	x := a.Transform(*b, true)
+	if !reflect.DeepEqual(b, x) {
+		t.Errorf("expected %+v but got %+v\n", b, x)
+	}
+}
+
+func TestTransformRetainRetainEmbed(t *testing.T) {
+	embed := Embed{"delta": New(nil).Insert("b", nil).Ops}
+	a := New(nil).Retain(1, nil).Delete(1)
+	b := New(nil).Retain(2, nil).RetainEmbed(embed, nil)
+
+	x := a.Transform(*b, true)
+	expected := New(nil).Retain(1, nil).RetainEmbed(embed, nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestInvertRetainEmbed(t *testing.T) {
+	RegisterEmbed("delta", deltaEmbed{})
+	defer UnregisterEmbed("delta")
+
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	inverseAttr := make(map[string]interface{})
+	inverseAttr["bold"] = nil
+	base := New(nil).Insert("a", nil).InsertEmbed(Embed{"delta": New(nil).Insert("a", nil).Ops}, nil)
+	delta := New(nil).Retain(1, nil).RetainEmbed(Embed{"delta": New(nil).Retain(1, nil).Insert("b", nil).Ops}, attr)
+
+	x := delta.Invert(*base)
+	expected := New(nil).Retain(1, nil).RetainEmbed(Embed{"delta": New(nil).Retain(1, nil).Delete(1).Ops}, inverseAttr)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if ret := base.Compose(*delta).Compose(*x); !reflect.DeepEqual(base, ret) {
+		t.Errorf("expected %+v but got %+v\n", base, ret)
+	}
+}
+
+func TestUnregisterEmbed(t *testing.T) {
+	RegisterEmbed("delta", deltaEmbed{})
+	if _, ok := getEmbedHandler("delta"); !ok {
+		t.Error("expected a handler for 'delta'")
+	}
+	UnregisterEmbed("delta")
+	if _, ok := getEmbedHandler("delta"); ok {
+		t.Error("expected no handler for 'delta'")
+	}
+}
@@ -0,0 +1,244 @@
+package delta
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode"
+)
+
+// ErrGraphemeSplit is used when a change would split a user-perceived character, like an accented
+// letter written with a combining mark, or an emoji joined with zero width joiners
+var ErrGraphemeSplit = errors.New("change splits a grapheme cluster")
+
+const zeroWidthJoiner = '\u200D'
+
+// GraphemeSplits returns the offsets in doc where a retain or a delete of the Delta starts or ends
+// in the middle of a grapheme cluster. Offsets use the current LengthMode
+func (d *Delta) GraphemeSplits(doc Delta) []int {
+	edges := graphemeEdges(graphemeText(doc))
+	var splits []int
+	iter := NewIterator(d.Ops)
+	pos := 0
+	for iter.HasNext() {
+		if iter.PeekType() == OpInsert {
+			iter.NextOp()
+			continue
+		}
+		op := iter.NextOp()
+		pos += OpsLength(op)
+		if !iter.HasNext() && op.Delete == nil && len(op.Attributes) == 0 {
+			// a trailing retain doesn't change anything
+			break
+		}
+		if !isGraphemeEdge(edges, pos) {
+			splits = append(splits, pos)
+		}
+	}
+	return splits
+}
+
+// CheckGraphemes returns an error that wraps ErrGraphemeSplit if applying the Delta on top of doc
+// would split a grapheme cluster, see GraphemeSplits
+func (d *Delta) CheckGraphemes(doc Delta) error {
+	if splits := d.GraphemeSplits(doc); len(splits) > 0 {
+		return fmt.Errorf("%w at %v", ErrGraphemeSplit, splits)
+	}
+	return nil
+}
+
+// SnapGraphemes returns a copy of the Delta where every retain and delete that splits a grapheme
+// cluster of doc is widened to the nearest cluster edge. Deletes win over formats,
+// and formats win over plain retains, so deleting half an emoji deletes all of it.
+// Inserts in the middle of a cluster move with the boundary they sit on
+func (d *Delta) SnapGraphemes(doc Delta) *Delta {
+	edges := graphemeEdges(graphemeText(doc))
+	// ends holds where every retain and delete ends in doc
+	ends := make([]int, len(d.Ops))
+	pos := 0
+	for i, op := range d.Ops {
+		if !op.isInsert() {
+			pos += OpsLength(op)
+		}
+		ends[i] = pos
+	}
+
+	prev := 0
+	for i, op := range d.Ops {
+		if op.isInsert() {
+			continue
+		}
+		end := ends[i]
+		if !isGraphemeEdge(edges, end) {
+			// the rest of the document is retained
+			var next Op
+			for _, o := range d.Ops[i+1:] {
+				if !o.isInsert() {
+					next = o
+					break
+				}
+			}
+			edge := sort.SearchInts(edges, end)
+			if snapWeight(next) > snapWeight(op) {
+				end = edges[edge-1]
+			} else {
+				end = edges[edge]
+			}
+		}
+		ends[i] = max(end, prev)
+		prev = ends[i]
+	}
+
+	delta := New(nil)
+	start := 0
+	for i, op := range d.Ops {
+		if op.isInsert() {
+			delta.Push(op.clone())
+			continue
+		}
+		length := ends[i] - start
+		start = ends[i]
+		if length <= 0 {
+			continue
+		}
+		newOp := op.clone()
+		if newOp.Delete != nil {
+			newOp.Delete = &length
+		} else if newOp.Retain != nil {
+			newOp.Retain = &length
+		}
+		delta.Push(newOp)
+	}
+	return delta.Chop()
+}
+
+// snapWeight tells SnapGraphemes which side of a split boundary grows, the highest one does
+func snapWeight(op Op) int {
+	switch {
+	case op.Delete != nil:
+		return 2
+	case len(op.Attributes) > 0 || op.RetainEmbed != nil:
+		return 1
+	}
+	return 0
+}
+
+// graphemeText returns the text of doc, ops that are not text, like embeds, count as one control
+// character per unit of length, so they always stand on their own
+func graphemeText(doc Delta) []rune {
+	var text []rune
+	for _, op := range doc.Ops {
+		if op.Insert != nil {
+			text = append(text, op.Insert...)
+			continue
+		}
+		for i := OpsLength(op); i > 0; i-- {
+			text = append(text, 0)
+		}
+	}
+	return text
+}
+
+// graphemeEdges returns the sorted offsets, in the current LengthMode, where text can be split
+// without breaking a grapheme cluster, including 0 and the length of text
+func graphemeEdges(text []rune) []int {
+	mode := CurrentLengthMode()
+	edges := []int{0}
+	offset, regional := 0, 0
+	for i, r := range text {
+		if i > 0 && graphemeBreak(text[i-1], r, regional) {
+			edges = append(edges, offset)
+		}
+		if isRegionalIndicator(r) {
+			regional++
+		} else {
+			regional = 0
+		}
+		offset += runeLength(r, mode)
+	}
+	if len(text) > 0 {
+		edges = append(edges, offset)
+	}
+	return edges
+}
+
+// isGraphemeEdge tells you if offset is in edges, offsets past the end of the text are always edges
+func isGraphemeEdge(edges []int, offset int) bool {
+	i := sort.SearchInts(edges, offset)
+	return i == len(edges) || edges[i] == offset
+}
+
+// graphemeBreak tells you if there is a grapheme cluster boundary between prev and r.
+// regional is the number of regional indicators right before r.
+// It follows the rules of Unicode Standard Annex #29 that matter for editing, without Prepend
+// characters and only approximating extended pictographics
+func graphemeBreak(prev, r rune, regional int) bool {
+	switch {
+	case prev == '\r' && r == '\n':
+		return false
+	case isGraphemeControl(prev) || isGraphemeControl(r):
+		return true
+	case hangulJoins(prev, r):
+		return false
+	case isGraphemeExtend(r) || r == zeroWidthJoiner || unicode.Is(unicode.Mc, r):
+		return false
+	case prev == zeroWidthJoiner && isPictographic(r):
+		return false
+	case isRegionalIndicator(prev) && isRegionalIndicator(r):
+		// flags are pairs of regional indicators
+		return regional%2 == 0
+	}
+	return true
+}
+
+func isGraphemeControl(r rune) bool {
+	return unicode.IsControl(r) || r == '\u2028' || r == '\u2029'
+}
+
+func isGraphemeExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me) ||
+		r == '\u200C' ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) || // skin tone modifiers
+		(r >= 0xE0020 && r <= 0xE007F) // tags
+}
+
+func isPictographic(r rune) bool {
+	return unicode.Is(unicode.So, r) || (r >= 0x1F000 && r <= 0x1FAFF)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// hangulJoins tells you if the Hangul jamo or syllables prev and r are part of the same syllable
+func hangulJoins(prev, r rune) bool {
+	p, n := hangulType(prev), hangulType(r)
+	switch p {
+	case 'L':
+		return n == 'L' || n == 'V' || n == 'v' || n == 't'
+	case 'V', 'v':
+		return n == 'V' || n == 'T'
+	case 'T', 't':
+		return n == 'T'
+	}
+	return false
+}
+
+// hangulType returns L, V or T for leading, vowel and trailing jamo,
+// v for LV syllables, t for LVT syllables and 0 for anything else
+func hangulType(r rune) byte {
+	switch {
+	case (r >= 0x1100 && r <= 0x115F) || (r >= 0xA960 && r <= 0xA97C):
+		return 'L'
+	case (r >= 0x1160 && r <= 0x11A7) || (r >= 0xD7B0 && r <= 0xD7C6):
+		return 'V'
+	case (r >= 0x11A8 && r <= 0x11FF) || (r >= 0xD7CB && r <= 0xD7FB):
+		return 'T'
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return 'v'
+		}
+		return 't'
+	}
+	return 0
+}
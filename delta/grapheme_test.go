@@ -0,0 +1,111 @@
+package delta
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGraphemeEdges(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected []int
+	}{
+		{"", []int{0}},
+		{"abc", []int{0, 1, 2, 3}},
+		{"e\u0301a", []int{0, 2, 3}},
+		{"a\r\nb", []int{0, 1, 3, 4}},
+		{"👍🏽!", []int{0, 4, 5}},
+		{"👨\u200D👩\u200D👧x", []int{0, 8, 9}},
+		{"🇫🇷🇩🇪", []int{0, 4, 8}},
+		{"각가", []int{0, 3, 4}},
+	}
+	for _, c := range cases {
+		if x := graphemeEdges([]rune(c.text)); !reflect.DeepEqual(c.expected, x) {
+			t.Errorf("%q: expected %+v but got %+v\n", c.text, c.expected, x)
+		}
+	}
+}
+
+func TestGraphemeSplits(t *testing.T) {
+	doc := New(nil).Insert("cafe\u0301 👍🏽", nil)
+
+	change := New(nil).Retain(4, nil).Delete(1)
+	if x := change.GraphemeSplits(*doc); !reflect.DeepEqual([]int{4}, x) {
+		t.Errorf("expected [4] but got %+v\n", x)
+	}
+	if err := change.CheckGraphemes(*doc); !errors.Is(err, ErrGraphemeSplit) {
+		t.Errorf("expected ErrGraphemeSplit but got %+v\n", err)
+	}
+
+	change = New(nil).Retain(8, map[string]interface{}{"bold": true})
+	if x := change.GraphemeSplits(*doc); !reflect.DeepEqual([]int{8}, x) {
+		t.Errorf("expected [8] but got %+v\n", x)
+	}
+
+	change = New(nil).Retain(3, nil).Delete(2).Insert("é", nil)
+	if err := change.CheckGraphemes(*doc); err != nil {
+		t.Error("expected no error but got ", err)
+	}
+
+	// a trailing retain doesn't change anything
+	change = New(nil).Delete(1).Retain(3, nil)
+	if err := change.CheckGraphemes(*doc); err != nil {
+		t.Error("expected no error but got ", err)
+	}
+}
+
+func TestSnapGraphemesDelete(t *testing.T) {
+	doc := New(nil).Insert("cafe\u0301 👍🏽", nil)
+	change := New(nil).Retain(4, nil).Delete(1)
+	expected := New(nil).Retain(3, nil).Delete(2)
+	x := change.SnapGraphemes(*doc)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if err := x.CheckGraphemes(*doc); err != nil {
+		t.Error("expected no error but got ", err)
+	}
+
+	// deleting half of the emoji deletes all of it and its skin tone
+	change = New(nil).Retain(6, nil).Delete(1)
+	expected = New(nil).Retain(6, nil).Delete(4)
+	if x := change.SnapGraphemes(*doc); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestSnapGraphemesFormat(t *testing.T) {
+	bold := map[string]interface{}{"bold": true}
+	doc := New(nil).Insert("cafe\u0301!", nil)
+
+	change := New(nil).Retain(1, nil).Retain(3, bold)
+	expected := New(nil).Retain(1, nil).Retain(4, bold)
+	if x := change.SnapGraphemes(*doc); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+
+	// the delete wins over the format
+	change = New(nil).Retain(4, bold).Delete(2)
+	expected = New(nil).Retain(3, bold).Delete(3)
+	if x := change.SnapGraphemes(*doc); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestSnapGraphemesInsert(t *testing.T) {
+	doc := New(nil).Insert("e\u0301a", nil)
+	change := New(nil).Retain(1, nil).Insert("x", nil)
+	expected := New(nil).Retain(2, nil).Insert("x", nil)
+	if x := change.SnapGraphemes(*doc); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestSnapGraphemesUnchanged(t *testing.T) {
+	doc := New(nil).Insert("Hello", nil).InsertEmbed(Embed{"image": "a.png"}, nil)
+	change := New(nil).Retain(2, nil).Delete(1).Insert("y", nil).Retain(3, map[string]interface{}{"bold": true})
+	if x := change.SnapGraphemes(*doc); !reflect.DeepEqual(change, x) {
+		t.Errorf("expected %+v but got %+v\n", change, x)
+	}
+}
@@ -0,0 +1,186 @@
+package delta
+
+import (
+	"reflect"
+	"testing"
+)
+
+// scribble changes everything that can be changed in place in the ops of d
+func scribble(d *Delta) {
+	for i := range d.Ops {
+		op := &d.Ops[i]
+		for j := range op.Insert {
+			op.Insert[j] = '#'
+		}
+		// write past the end too, in case someone appends to our text
+		if op.Insert != nil {
+			op.Insert = append(op.Insert, '#')
+		}
+		for _, m := range []map[string]interface{}{op.Attributes, op.InsertEmbed, op.RetainEmbed} {
+			for k, v := range m {
+				if nested, ok := v.(map[string]interface{}); ok {
+					nested["scribbled"] = true
+				} else {
+					m[k] = "scribbled"
+				}
+			}
+		}
+		if op.Retain != nil {
+			*op.Retain += 100
+		}
+		if op.Delete != nil {
+			*op.Delete += 100
+		}
+	}
+	d.Ops = append(d.Ops, Op{Insert: []rune("#")})
+}
+
+// expectNoAliasing calls fn with copies of inputs, then scribbles on the returned Delta
+// and on the inputs, and fails if that changed the other side
+func expectNoAliasing(t *testing.T, name string, fn func(a, b *Delta) *Delta, a, b *Delta) {
+	t.Helper()
+	// give the inputs spare capacity, so appends could write through
+	a = New(append(make([]Op, 0, len(a.Ops)+8), a.Clone().Ops...))
+	b = New(append(make([]Op, 0, len(b.Ops)+8), b.Clone().Ops...))
+	for i := range a.Ops {
+		if a.Ops[i].Insert != nil {
+			a.Ops[i].Insert = append(make([]rune, 0, len(a.Ops[i].Insert)+8), a.Ops[i].Insert...)
+		}
+	}
+	aBefore, bBefore := a.Clone(), b.Clone()
+
+	ret := fn(a, b)
+	retBefore := ret.Clone()
+	scribble(ret)
+	if !reflect.DeepEqual(aBefore, a) || !reflect.DeepEqual(bBefore, b) {
+		t.Errorf("%s: changing the result changed its inputs\n", name)
+	}
+
+	ret = fn(a, b)
+	scribble(a)
+	scribble(b)
+	if !reflect.DeepEqual(retBefore, ret) {
+		t.Errorf("%s: changing the inputs changed the result\n", name)
+	}
+}
+
+func TestNoAliasing(t *testing.T) {
+	attrs := func() AttributeMap {
+		return AttributeMap{"bold": true, "style": map[string]interface{}{"color": "red"}}
+	}
+	doc := New(nil).
+		Insert("Hello", attrs()).
+		Insert(" World", nil).
+		InsertEmbed(Embed{"image": map[string]interface{}{"src": "a.png"}}, attrs()).
+		Insert("\n", nil)
+	change := New(nil).
+		Retain(2, attrs()).
+		Insert("abc", attrs()).
+		Delete(4).
+		Retain(5, nil).
+		Retain(1, AttributeMap{"width": "10"})
+	other := New(nil).Insert("xyz", attrs()).Retain(3, attrs()).Delete(1)
+
+	cases := []struct {
+		name string
+		fn   func(a, b *Delta) *Delta
+		a, b *Delta
+	}{
+		{"Clone", func(a, b *Delta) *Delta { return a.Clone() }, doc, nil},
+		{"Concat", func(a, b *Delta) *Delta { return a.Concat(*b) }, doc, doc},
+		{"Compose", func(a, b *Delta) *Delta { return a.Compose(*b) }, doc, change},
+		{"Compose changes", func(a, b *Delta) *Delta { return a.Compose(*b) }, change, other},
+		{"Transform", func(a, b *Delta) *Delta { return a.Transform(*b, true) }, other, change},
+		{"Invert", func(a, b *Delta) *Delta { return a.Invert(*b) }, change, doc},
+		{"Diff", func(a, b *Delta) *Delta {
+			x, _ := a.Diff(*b)
+			return x
+		}, doc, doc.Compose(*change)},
+		{"Slice", func(a, b *Delta) *Delta { return a.Slice(3, 8) }, doc, nil},
+		{"Filter", func(a, b *Delta) *Delta {
+			return a.Filter(func(op Op, index int) bool { return true })
+		}, doc, nil},
+		{"Map", func(a, b *Delta) *Delta {
+			return a.Map(func(op Op, index int) Op { return op })
+		}, doc, nil},
+		{"Partition", func(a, b *Delta) *Delta {
+			x, _ := a.Partition(func(op Op, index int) bool { return true })
+			return x
+		}, doc, nil},
+		{"ApplyTo", func(a, b *Delta) *Delta {
+			x, _ := b.ApplyTo(*a)
+			return x
+		}, doc, change},
+		{"SnapGraphemes", func(a, b *Delta) *Delta { return b.SnapGraphemes(*a) }, doc, change},
+		{"Push", func(a, b *Delta) *Delta {
+			x := New(nil)
+			for _, op := range a.Ops {
+				x.Push(op)
+			}
+			return x
+		}, doc, nil},
+	}
+	for _, c := range cases {
+		b := c.b
+		if b == nil {
+			b = New(nil)
+		}
+		expectNoAliasing(t, c.name, c.fn, c.a, b)
+	}
+}
+
+func TestConcatDoesNotWriteIntoReceiver(t *testing.T) {
+	ops := make([]Op, 1, 4)
+	ops[0] = Op{Insert: []rune("a")}
+	a := New(ops)
+	a.Concat(*New(nil).Retain(1, nil).Insert("b", nil))
+	a.Concat(*New(nil).Retain(1, nil).Insert("c", nil))
+	if extra := ops[:2]; extra[1].Retain != nil {
+		t.Errorf("Concat wrote into the backing array of the receiver: %+v\n", extra)
+	}
+}
+
+func TestPushDoesNotAppendIntoCallerText(t *testing.T) {
+	text := make([]rune, 1, 8)
+	text[0] = 'a'
+	a := New([]Op{{Insert: text}})
+	b := New([]Op{{Insert: text}})
+	a.Push(Op{Insert: []rune("b")})
+	b.Push(Op{Insert: []rune("c")})
+	if string(a.Ops[0].Insert) != "ab" || string(b.Ops[0].Insert) != "ac" {
+		t.Errorf("expected ab and ac but got %s and %s\n", string(a.Ops[0].Insert), string(b.Ops[0].Insert))
+	}
+}
+
+func TestPushDoesNotWriteIntoCallerOps(t *testing.T) {
+	ops := []Op{{Insert: []rune("a")}}
+	a := New(ops)
+	a.Insert("b", nil).Insert("c", nil)
+	if string(ops[0].Insert) != "a" || string(a.Ops[0].Insert) != "abc" {
+		t.Errorf("expected a and abc but got %s and %s\n", string(ops[0].Insert), string(a.Ops[0].Insert))
+	}
+
+	// merges into text the Delta owns happen in place, clones must not see them
+	b := a.Clone()
+	a.Insert("d", nil)
+	b.Insert("e", nil)
+	if string(a.Ops[0].Insert) != "abcd" || string(b.Ops[0].Insert) != "abce" {
+		t.Errorf("expected abcd and abce but got %s and %s\n", string(a.Ops[0].Insert), string(b.Ops[0].Insert))
+	}
+}
+
+func TestBuilderDoesNotWriteIntoPushedText(t *testing.T) {
+	src := New(nil).Insert("abc", nil)
+	it := NewIterator(src.Ops)
+	x := NewBuilder(0).Push(it.Next(1)).Insert("X", nil).Delta()
+	if string(src.Ops[0].Insert) != "abc" || string(x.Ops[0].Insert) != "aX" {
+		t.Errorf("expected abc and aX but got %s and %s\n", string(src.Ops[0].Insert), string(x.Ops[0].Insert))
+	}
+
+	// a text with spare capacity given to Push directly
+	text := append(make([]rune, 0, 8), 'a')
+	x = NewBuilder(0).Push(Op{Insert: text}).Insert("X", nil).Delta()
+	if text = text[:2]; string(text) != "a\x00" || string(x.Ops[0].Insert) != "aX" {
+		t.Errorf("expected a and aX but got %q and %s\n", string(text), string(x.Ops[0].Insert))
+	}
+}
@@ -15,10 +15,16 @@ func NewIterator(ops []Op) Iterator {
 }
 
 // Iterator holds a list of Op, an Index and Offset
+// Offset is measured in the current LengthMode, so it can be different from the index of a rune in Insert
 type Iterator struct {
 	Ops    []Op
 	Index  int
 	Offset int
+	// the rune that starts at textOffset in the text insert at textIndex,
+	// so we don't walk long inserts from the start on every call to Next
+	textIndex  int
+	textOffset int
+	textRune   int
 }
 
 // HasNext returns true if we have more ops
@@ -26,15 +32,12 @@ func (x *Iterator) HasNext() bool {
 	return x.PeekLength() < math.MaxInt64
 }
 
-// Next moves the index on item
+// Next returns up to length of the current op and moves past it, use NextOp for all of it.
+// Past the last op it returns a zero Op, see HasNext.
+// It panics with ErrSurrogateSplit if length ends in the middle of a surrogate pair
 func (x *Iterator) Next(length int) Op {
-	// TODO: see where we need to allow passing infinity as length
-	// js code does that if length is missing
 	if len(x.Ops) <= x.Index {
-		m := math.MaxInt64
-		return Op{
-			Retain: &m,
-		}
+		return Op{}
 	}
 
 	nextOp := x.Ops[x.Index]
@@ -42,6 +45,16 @@ func (x *Iterator) Next(length int) Op {
 	opLength := OpsLength(nextOp)
 	if length >= opLength-offset {
 		length = opLength - offset
+	}
+	var text []rune
+	if nextOp.InsertEmbed == nil && nextOp.Insert != nil {
+		// find the runes to return before we move, so a surrogate split leaves the iterator untouched
+		start := x.runeAt(nextOp.Insert, offset)
+		end := x.runeAt(nextOp.Insert, offset+length)
+		// without spare capacity, so appending to the returned text can't write into the op
+		text = nextOp.Insert[start:end:end]
+	}
+	if offset+length >= opLength {
 		x.Index++
 		x.Offset = 0
 	} else {
@@ -57,27 +70,49 @@ func (x *Iterator) Next(length int) Op {
 	if nextOp.Retain != nil {
 		retOp.Retain = &length
 	}
-	if nextOp.Insert != nil {
-		// when using Go's slice syntax to extract characters from a string, note that the
-		// number after the ":" isn't the number of characters to take, but the position, starting from 0
-		// to extract. This is different than the way substr in js is implemented
-		// Also, using a :length greater than the actual len(str) panics
-		l := length + offset // because of how Go's slice[a:b] work
-		if xx := len(nextOp.Insert); xx < l {
-			l = xx
-		}
-		str := (nextOp.Insert)[offset:l]
-		retOp.Insert = str
+	if nextOp.RetainEmbed != nil {
+		retOp.RetainEmbed = nextOp.RetainEmbed
+	}
+	if nextOp.InsertEmbed != nil {
+		// embeds have a length of 1, they can't be split
+		retOp.InsertEmbed = nextOp.InsertEmbed
+	} else if nextOp.Insert != nil {
+		retOp.Insert = text
 	}
 	return retOp
 }
 
-// Peek returns the current Op without advancing the index
-func (x *Iterator) Peek() Op {
-	return x.Ops[x.Index]
+// runeAt returns the index of the rune that starts at offset in text, the insert of the op at x.Index.
+// It panics with ErrSurrogateSplit if offset is in the middle of a surrogate pair
+func (x *Iterator) runeAt(text []rune, offset int) int {
+	from, fromOffset := 0, 0
+	if x.textIndex == x.Index && x.textOffset <= offset {
+		from, fromOffset = x.textRune, x.textOffset
+	}
+	i := runeIndex(text, from, fromOffset, offset)
+	x.textIndex, x.textOffset, x.textRune = x.Index, offset, i
+	return i
+}
+
+// peekText returns the text left in the op at the current index, nil if it's not a text insert
+func (x *Iterator) peekText() []rune {
+	if len(x.Ops) <= x.Index || x.Ops[x.Index].InsertEmbed != nil {
+		return nil
+	}
+	text := x.Ops[x.Index].Insert
+	return text[x.runeAt(text, x.Offset):]
+}
+
+// Peek returns the current Op without advancing the index, or false if there are no more ops
+func (x *Iterator) Peek() (Op, bool) {
+	if len(x.Ops) <= x.Index {
+		return Op{}, false
+	}
+	return x.Ops[x.Index], true
 }
 
-// PeekLength returns the length of the Op at the current index
+// PeekLength returns the length left in the Op at the current index,
+// or math.MaxInt64 past the last op, where a Delta implicitly retains the rest of the document
 func (x *Iterator) PeekLength() int {
 	if len(x.Ops) > x.Index {
 		return OpsLength(x.Ops[x.Index]) - x.Offset
@@ -85,18 +120,31 @@ func (x *Iterator) PeekLength() int {
 	return math.MaxInt64
 }
 
-// PeekType tells you the type of operation at the current Index
-func (x *Iterator) PeekType() string {
-	if len(x.Ops) > x.Index { //TODO may be a bug getting the last item (highest index)
-		if x.Ops[x.Index].Delete != nil {
-			return "delete"
-		}
-		if x.Ops[x.Index].Retain != nil {
-			return "retain"
-		}
-		if x.Ops[x.Index].Insert != nil {
-			return "insert"
-		}
-	}
-	return "retain"
+// PeekType tells you the type of operation at the current Index.
+// Past the last op it returns OpRetain, since a Delta implicitly retains the rest of the document
+func (x *Iterator) PeekType() OpType {
+	if op, ok := x.Peek(); ok && op.Type() != "" {
+		return op.Type()
+	}
+	return OpRetain
+}
+
+// NextOp returns what is left of the current op and moves to the next one, like Next(math.MaxInt64)
+func (x *Iterator) NextOp() Op {
+	return x.Next(math.MaxInt64)
+}
+
+// Rest returns the ops left to iterate, starting with what is left of the current op, without moving the iterator
+func (x *Iterator) Rest() []Op {
+	if !x.HasNext() {
+		return nil
+	}
+	if x.Offset == 0 {
+		return append([]Op(nil), x.Ops[x.Index:]...)
+	}
+	saved := *x
+	next := x.NextOp()
+	rest := append([]Op{next}, x.Ops[x.Index:]...)
+	*x = saved
+	return rest
 }
@@ -2,6 +2,7 @@ package delta
 
 import (
 	"math"
+	"reflect"
 	"testing"
 )
 
@@ -51,17 +52,14 @@ func TestNext(t *testing.T) {
 			t.Errorf("failed to call Next(), '%+v' diff than '%+v'\n", *n.Delete, *nn.Delete)
 		}
 	}
-	n := iter.Next(math.MaxInt64)
-	if *n.Retain != math.MaxInt64 {
-		t.Error("didn't get MaxInt64, got: ", *n.Retain)
-	}
-	n = iter.Next(4)
-	if *n.Retain != math.MaxInt64 {
-		t.Error("didn't get MaxInt64, got: ", *n.Retain)
+	// past the end we get a zero Op
+	for _, n := range []Op{iter.Next(math.MaxInt64), iter.Next(4), iter.NextOp()} {
+		if !reflect.DeepEqual(Op{}, n) {
+			t.Errorf("expected a zero Op past the end but got %+v\n", n)
+		}
 	}
-	n = iter.Next(math.MaxInt64)
-	if *n.Retain != math.MaxInt64 {
-		t.Error("didn't get MaxInt64, got: ", *n.Retain)
+	if iter.HasNext() {
+		t.Error("expected no more ops past the end")
 	}
 }
 
@@ -157,3 +155,107 @@ func TestPeekLengthNoOpsLeft(t *testing.T) {
 		t.Errorf("expected 'math.MaxInt64' but got '%d'\n", x)
 	}
 }
+
+func TestNextEmbed(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["width"] = "300"
+	embed := Embed{"image": "http://quilljs.com"}
+	delta := New(nil).Insert("Hello", nil).InsertEmbed(embed, attr).Insert("World", nil)
+	iter := NewIterator(delta.Ops)
+
+	iter.Next(math.MaxInt64)
+	if s := iter.PeekType(); s != "insert" {
+		t.Errorf("iter.PeekType() expected 'insert' but got '%s'\n", s)
+	}
+	if x := iter.PeekLength(); x != 1 {
+		t.Errorf("expected 1 but got '%d'\n", x)
+	}
+	n := iter.Next(3)
+	if n.InsertEmbed["image"] != "http://quilljs.com" {
+		t.Errorf("didn't get the embed, got: %+v\n", n)
+	}
+	if n.Insert != nil {
+		t.Errorf("expected nil text insert, got: %+v\n", n)
+	}
+	if n.Attributes["width"] != "300" {
+		t.Errorf("didn't get correct attr, got: %+v\n", n.Attributes)
+	}
+	n = iter.Next(2)
+	if string(n.Insert) != "Wo" {
+		t.Error("didn't get 'Wo', got: ", string(n.Insert))
+	}
+}
+
+func TestPeek(t *testing.T) {
+	delta := New(nil).Insert("Hello", nil).Retain(3, nil)
+	iter := NewIterator(delta.Ops)
+	if op, ok := iter.Peek(); !ok || string(op.Insert) != "Hello" {
+		t.Errorf("expected Hello but got %+v, %v\n", op, ok)
+	}
+	iter.NextOp()
+	iter.NextOp()
+	if op, ok := iter.Peek(); ok || !op.IsNil() {
+		t.Errorf("expected no op but got %+v, %v\n", op, ok)
+	}
+	if x := iter.PeekType(); x != OpRetain {
+		t.Error("expected retain past the end but got ", x)
+	}
+}
+
+func TestNextOp(t *testing.T) {
+	delta := New(nil).Insert("Hello", nil).Delete(3)
+	iter := NewIterator(delta.Ops)
+	iter.Next(2)
+	if n := iter.NextOp(); string(n.Insert) != "llo" {
+		t.Error("didn't get 'llo', got: ", string(n.Insert))
+	}
+	if n := iter.NextOp(); n.Delete == nil || *n.Delete != 3 {
+		t.Errorf("expected a delete of 3 but got %+v\n", n)
+	}
+	if iter.HasNext() {
+		t.Error("expected no more ops")
+	}
+}
+
+func TestRest(t *testing.T) {
+	bold := map[string]interface{}{"bold": true}
+	delta := New(nil).Insert("Hello", bold).Retain(3, nil).Delete(4)
+	iter := NewIterator(delta.Ops)
+	if x := iter.Rest(); !reflect.DeepEqual(delta.Ops, x) {
+		t.Errorf("expected %+v but got %+v\n", delta.Ops, x)
+	}
+	iter.Next(2)
+	expected := New(nil).Insert("llo", bold).Retain(3, nil).Delete(4).Ops
+	if x := iter.Rest(); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	// Rest doesn't move the iterator
+	if n := iter.Next(1); string(n.Insert) != "l" {
+		t.Error("didn't get 'l', got: ", string(n.Insert))
+	}
+	iter.NextOp()
+	iter.NextOp()
+	iter.NextOp()
+	if x := iter.Rest(); x != nil {
+		t.Errorf("expected nil but got %+v\n", x)
+	}
+}
+
+func TestOpType(t *testing.T) {
+	one := 1
+	cases := map[OpType]Op{
+		OpInsert: {Insert: []rune("a")},
+		OpRetain: {RetainEmbed: Embed{"table": nil}},
+		OpDelete: {Delete: &one},
+		"":       {},
+	}
+	for expected, op := range cases {
+		if x := op.Type(); x != expected {
+			t.Errorf("expected %q but got %q\n", expected, x)
+		}
+	}
+	embed := Op{InsertEmbed: Embed{"image": "a.png"}}
+	if x := embed.Type(); x != OpInsert {
+		t.Errorf("expected insert but got %q\n", x)
+	}
+}
@@ -0,0 +1,122 @@
+package delta
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+// ErrSurrogateSplit is used when an op would split a character that takes two UTF-16 code units,
+// like most emoji, in two halves. Iterator.Next, Compose, Invert, Slice and the Document methods
+// panic with an error that wraps it when given such an offset. Transform never splits text, it doesn't.
+// Use CheckSurrogates, or ApplyTo, to get an error instead
+var ErrSurrogateSplit = errors.New("cannot split a surrogate pair")
+
+// LengthMode tells the package how to measure text inserts, and so how to read every
+// retain, delete and index. It applies to the whole package, see SetLengthMode
+type LengthMode int32
+
+const (
+	// UTF16 counts UTF-16 code units, the way JavaScript and so Quill do. Characters outside
+	// of the Basic Multilingual Plane, like most emoji, have a length of 2. It's the default
+	UTF16 LengthMode = iota
+	// Runes counts every rune (Unicode code point) as 1
+	Runes
+)
+
+var lengthMode atomic.Int32
+
+// SetLengthMode changes how text is measured for the whole package.
+// Deltas coming from Quill in a browser need UTF16, which is the default
+func SetLengthMode(mode LengthMode) {
+	lengthMode.Store(int32(mode))
+}
+
+// CurrentLengthMode returns the LengthMode in use
+func CurrentLengthMode() LengthMode {
+	return LengthMode(lengthMode.Load())
+}
+
+// runeLength returns the length of r in the current LengthMode
+func runeLength(r rune, mode LengthMode) int {
+	if mode == UTF16 && r > 0xFFFF {
+		return 2
+	}
+	return 1
+}
+
+// textLength returns the length of text in the current LengthMode
+func textLength(text []rune) int {
+	mode := CurrentLengthMode()
+	if mode == Runes {
+		return len(text)
+	}
+	length := 0
+	for _, r := range text {
+		length += runeLength(r, mode)
+	}
+	return length
+}
+
+// runeIndex walks text from the rune at index from, which starts after fromLength,
+// and returns the index of the rune that starts after length.
+// It panics with ErrSurrogateSplit if length falls in the middle of a rune
+func runeIndex(text []rune, from, fromLength, length int) int {
+	mode := CurrentLengthMode()
+	if mode == Runes {
+		return length
+	}
+	i, l := from, fromLength
+	for l < length && i < len(text) {
+		l += runeLength(text[i], mode)
+		i++
+	}
+	if l > length {
+		panic(fmt.Errorf("%w: offset %d of %q", ErrSurrogateSplit, length, string(text)))
+	}
+	return i
+}
+
+// CheckSurrogates returns an *OpError that wraps ErrSurrogateSplit for the first retain or delete
+// of the Delta that ends in the middle of a surrogate pair of doc, which would make Compose panic.
+// It always returns nil in the Runes LengthMode, where nothing can be split
+func (d *Delta) CheckSurrogates(doc Delta) error {
+	if CurrentLengthMode() != UTF16 {
+		return nil
+	}
+	splits := surrogateSplits(doc)
+	if len(splits) == 0 {
+		return nil
+	}
+	pos := 0
+	for i, op := range d.Ops {
+		if op.isInsert() {
+			continue
+		}
+		pos += OpsLength(op)
+		if j := sort.SearchInts(splits, pos); j < len(splits) && splits[j] == pos {
+			return &OpError{Index: i, Err: fmt.Errorf("%w at offset %d", ErrSurrogateSplit, pos)}
+		}
+	}
+	return nil
+}
+
+// surrogateSplits returns the sorted UTF-16 offsets of doc that fall in the middle of a surrogate pair
+func surrogateSplits(doc Delta) []int {
+	var splits []int
+	offset := 0
+	for _, op := range doc.Ops {
+		if op.InsertEmbed != nil || op.Insert == nil {
+			offset += OpsLength(op)
+			continue
+		}
+		for _, r := range op.Insert {
+			if runeLength(r, UTF16) == 2 {
+				splits = append(splits, offset+1)
+			}
+			offset += runeLength(r, UTF16)
+		}
+	}
+	return splits
+}
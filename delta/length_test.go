@@ -0,0 +1,214 @@
+package delta
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLengthModeDefault(t *testing.T) {
+	if x := CurrentLengthMode(); x != UTF16 {
+		t.Error("expected UTF16 to be the default but got ", x)
+	}
+}
+
+func TestOpsLengthUTF16(t *testing.T) {
+	op := Op{Insert: []rune("a😀b")}
+	if x := OpsLength(op); x != 4 {
+		t.Error("expected 4 but got ", x)
+	}
+	SetLengthMode(Runes)
+	defer SetLengthMode(UTF16)
+	if x := OpsLength(op); x != 3 {
+		t.Error("expected 3 but got ", x)
+	}
+}
+
+func TestNextUTF16(t *testing.T) {
+	delta := New(nil).Insert("a😀b😀", nil)
+	iter := NewIterator(delta.Ops)
+
+	n := iter.Next(1)
+	if string(n.Insert) != "a" {
+		t.Error("didn't get 'a', got: ", string(n.Insert))
+	}
+	n = iter.Next(3)
+	if string(n.Insert) != "😀b" {
+		t.Error("didn't get '😀b', got: ", string(n.Insert))
+	}
+	if x := iter.PeekLength(); x != 2 {
+		t.Error("expected 2 but got ", x)
+	}
+	n = iter.Next(math.MaxInt64)
+	if string(n.Insert) != "😀" {
+		t.Error("didn't get '😀', got: ", string(n.Insert))
+	}
+	if iter.HasNext() {
+		t.Error("expected no more ops")
+	}
+}
+
+func TestNextSurrogateSplit(t *testing.T) {
+	delta := New(nil).Insert("a😀b", nil)
+	iter := NewIterator(delta.Ops)
+	iter.Next(1)
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, ErrSurrogateSplit) {
+				t.Errorf("expected ErrSurrogateSplit but got %+v\n", err)
+			}
+		}()
+		iter.Next(1)
+	}()
+	// the failed call didn't move the iterator
+	if x := iter.PeekLength(); x != 3 {
+		t.Error("expected 3 but got ", x)
+	}
+	if n := iter.Next(2); string(n.Insert) != "😀" {
+		t.Error("didn't get '😀', got: ", string(n.Insert))
+	}
+}
+
+func TestNextRunes(t *testing.T) {
+	SetLengthMode(Runes)
+	defer SetLengthMode(UTF16)
+	delta := New(nil).Insert("a😀b", nil)
+	iter := NewIterator(delta.Ops)
+	iter.Next(1)
+	if n := iter.Next(1); string(n.Insert) != "😀" {
+		t.Error("didn't get '😀', got: ", string(n.Insert))
+	}
+}
+
+func TestComposeUTF16(t *testing.T) {
+	a := New(nil).Insert("a😀b", nil)
+	b := New(nil).Retain(3, nil).Delete(1).Insert("c", nil)
+	expected := New(nil).Insert("a😀c", nil)
+	if x := a.Compose(*b); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+
+	SetLengthMode(Runes)
+	defer SetLengthMode(UTF16)
+	b = New(nil).Retain(2, nil).Delete(1).Insert("c", nil)
+	if x := a.Compose(*b); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestComposeSurrogateSplit(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrSurrogateSplit) {
+			t.Errorf("expected ErrSurrogateSplit but got %+v\n", err)
+		}
+	}()
+	a := New(nil).Insert("a😀b", nil)
+	b := New(nil).Retain(2, nil).Delete(1)
+	a.Compose(*b)
+}
+
+func TestTransformPositionUTF16(t *testing.T) {
+	delta := New(nil).Retain(1, nil).Insert("😀", nil)
+	if x := delta.TransformPosition(2, false); x != 4 {
+		t.Error("expected 4 but got ", x)
+	}
+	delta = New(nil).Delete(2)
+	if x := delta.TransformPosition(5, false); x != 3 {
+		t.Error("expected 3 but got ", x)
+	}
+}
+
+func TestTransformUTF16(t *testing.T) {
+	a := New(nil).Insert("😀", nil)
+	b := New(nil).Retain(1, nil).Insert("x", nil)
+	expected := New(nil).Retain(3, nil).Insert("x", nil)
+	if x := a.Transform(*b, true); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestSliceUTF16(t *testing.T) {
+	delta := New(nil).Insert("a😀b", nil)
+	expected := New(nil).Insert("😀b", nil)
+	if x := delta.Slice(1, 4); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if x := delta.Length(); x != 4 {
+		t.Error("expected 4 but got ", x)
+	}
+}
+
+func TestDiffUTF16(t *testing.T) {
+	a := New(nil).Insert("😀a😀", nil)
+	b := New(nil).Insert("😀b😀", nil)
+	expected := New(nil).Retain(2, nil).Insert("b", nil).Delete(1)
+	x, err := a.Diff(*b)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if ret := a.Compose(*x); !reflect.DeepEqual(b, ret) {
+		t.Errorf("expected %+v but got %+v\n", b, ret)
+	}
+}
+
+func TestEachLineUTF16(t *testing.T) {
+	delta := New(nil).Insert("😀a\n😀😀\nb", nil)
+	var lines []string
+	delta.EachLine(func(line Delta, attrs map[string]interface{}, index int) bool {
+		lines = append(lines, string(line.Ops[0].Insert))
+		return true
+	}, "\n")
+	if !reflect.DeepEqual([]string{"😀a", "😀😀", "b"}, lines) {
+		t.Errorf("expected [😀a 😀😀 b] but got %+v\n", lines)
+	}
+}
+
+func TestCheckSurrogates(t *testing.T) {
+	doc := New(nil).Insert("a😀b", nil).InsertEmbed(Embed{"image": "x"}, nil).Insert("😀", nil)
+	for _, change := range []*Delta{
+		New(nil).Retain(1, nil).Insert("x", nil).Delete(2),
+		New(nil).Retain(3, AttributeMap{"bold": true}).Retain(2, nil),
+		New(nil).Retain(4, nil).Insert("x", nil).Retain(3, nil),
+		New(nil).Insert("x", nil),
+	} {
+		if err := change.CheckSurrogates(*doc); err != nil {
+			t.Errorf("expected no error for %+v but got %v\n", change, err)
+		}
+	}
+
+	change := New(nil).Retain(1, nil).Insert("x", nil).Delete(1).Retain(1, nil)
+	err := change.CheckSurrogates(*doc)
+	var opErr *OpError
+	if !errors.Is(err, ErrSurrogateSplit) || !errors.As(err, &opErr) || opErr.Index != 2 {
+		t.Errorf("expected ErrSurrogateSplit at op 2 but got %v\n", err)
+	}
+	// in the middle of the last character of doc
+	if err := New(nil).Retain(6, nil).CheckSurrogates(*doc); !errors.Is(err, ErrSurrogateSplit) {
+		t.Errorf("expected ErrSurrogateSplit but got %v\n", err)
+	}
+
+	SetLengthMode(Runes)
+	defer SetLengthMode(UTF16)
+	if err := change.CheckSurrogates(*doc); err != nil {
+		t.Errorf("expected no error but got %v\n", err)
+	}
+}
+
+func TestTransformDoesNotSplitSurrogates(t *testing.T) {
+	// Transform takes inserts whole, so retains that would end inside one of its emoji don't matter
+	a := New(nil).Insert("😀a", nil)
+	b := New(nil).Retain(1, nil).Insert("x", nil)
+	expected := New(nil).Retain(4, nil).Insert("x", nil)
+	if x := a.Transform(*b, true); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if x := b.Transform(*a, false); !reflect.DeepEqual(a, x) {
+		t.Errorf("expected %+v but got %+v\n", a, x)
+	}
+}
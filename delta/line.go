@@ -0,0 +1,113 @@
+package delta
+
+import "sort"
+
+// Line is a line of a Document: its inline ops, without the newline that ends it,
+// and the block formats of that newline, like {"header": 1} or {"list": "bullet"}
+type Line struct {
+	Delta      Delta
+	Attributes AttributeMap
+	// Start is where the line starts in the document
+	Start int
+}
+
+// Length returns the length of the line, including its newline
+func (l Line) Length() int {
+	return l.Delta.Length() + 1
+}
+
+// End returns where the newline of the line is in the document
+func (l Line) End() int {
+	return l.Start + l.Delta.Length()
+}
+
+// Header returns the header level of the line, 0 if it's not a header
+func (l Line) Header() int {
+	return intAttribute(l.Attributes, "header")
+}
+
+// List returns the list type of the line, like bullet, ordered or checked, or "" if it's not in a list
+func (l Line) List() string {
+	list, _ := l.Attributes["list"].(string)
+	return list
+}
+
+// Indent returns the indentation level of the line, 0 if it's not indented
+func (l Line) Indent() int {
+	return intAttribute(l.Attributes, "indent")
+}
+
+// Align returns the alignment of the line, like center or right, or "" for the default alignment
+func (l Line) Align() string {
+	align, _ := l.Attributes["align"].(string)
+	return align
+}
+
+// Blockquote tells you if the line is in a blockquote
+func (l Line) Blockquote() bool {
+	return isSet(l.Attributes["blockquote"])
+}
+
+// CodeBlock tells you if the line is in a code block, its value can be true or the language of the code
+func (l Line) CodeBlock() bool {
+	return isSet(l.Attributes["code-block"])
+}
+
+// Format returns the change that applies formats to the block formats of the line in the document
+func (l Line) Format(formats AttributeMap) *Delta {
+	return New(nil).Retain(l.End(), nil).Retain(1, formats)
+}
+
+// Change returns change, which is relative to the start of the line, as a change to the whole document
+func (l Line) Change(change Delta) *Delta {
+	return New(nil).Retain(l.Start, nil).Concat(change).Chop()
+}
+
+// Lines returns the lines of the Document, see Line
+func (doc *Document) Lines() []Line {
+	var lines []Line
+	start := 0
+	doc.delta.EachLine(func(line Delta, attrs map[string]interface{}, _ int) bool {
+		lines = append(lines, Line{Delta: *line.Clone(), Attributes: AttributeMap(attrs).clone(), Start: start})
+		start += line.Length() + 1
+		return true
+	}, "\n")
+	return lines
+}
+
+// LineAt returns the line that has the character at index, or false if index is not in the Document
+func (doc *Document) LineAt(index int) (Line, bool) {
+	lines := doc.LineRange(index, 1)
+	if len(lines) == 0 {
+		return Line{}, false
+	}
+	return lines[0], true
+}
+
+// LineRange returns the lines that have a character between index and index+length,
+// or the line at index if length is 0
+func (doc *Document) LineRange(index, length int) []Line {
+	lines := doc.Lines()
+	end := index + max(length, 1)
+	first := sort.Search(len(lines), func(i int) bool {
+		return lines[i].End() >= index
+	})
+	last := first
+	for last < len(lines) && lines[last].Start < end {
+		last++
+	}
+	return lines[first:last]
+}
+
+// intAttribute returns the value of key as an int, 0 if it's missing or not a whole number
+func intAttribute(attrs AttributeMap, key string) int {
+	if n, ok := numberValue(attrs[key]); ok && n.IsInt() && n.Num().IsInt64() {
+		return int(n.Num().Int64())
+	}
+	return 0
+}
+
+// isSet tells you if a format is on, any value other than nil and false is
+func isSet(v interface{}) bool {
+	return v != nil && v != false
+}
@@ -0,0 +1,127 @@
+package delta
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func lineTestDocument(t *testing.T) *Document {
+	t.Helper()
+	return newTestDocument(t, New(nil).
+		Insert("Title", nil).Insert("\n", map[string]interface{}{"header": 1}).
+		Insert("one", map[string]interface{}{"bold": true}).Insert("\n", map[string]interface{}{"list": "bullet", "indent": json.Number("2")}).
+		Insert("\n", nil).
+		Insert("x := 1", nil).Insert("\n", map[string]interface{}{"code-block": "go"}).
+		Insert("quote", nil).Insert("\n", map[string]interface{}{"blockquote": true, "align": "center"}))
+}
+
+func TestDocumentLines(t *testing.T) {
+	lines := lineTestDocument(t).Lines()
+	expected := []Line{
+		{Delta: *New(nil).Insert("Title", nil), Attributes: AttributeMap{"header": 1}, Start: 0},
+		{Delta: *New(nil).Insert("one", map[string]interface{}{"bold": true}), Attributes: AttributeMap{"list": "bullet", "indent": json.Number("2")}, Start: 6},
+		{Delta: *New(nil), Attributes: nil, Start: 10},
+		{Delta: *New(nil).Insert("x := 1", nil), Attributes: AttributeMap{"code-block": "go"}, Start: 11},
+		{Delta: *New(nil).Insert("quote", nil), Attributes: AttributeMap{"blockquote": true, "align": "center"}, Start: 18},
+	}
+	if !reflect.DeepEqual(expected, lines) {
+		t.Errorf("expected %+v but got %+v\n", expected, lines)
+	}
+	if x := lines[1].Length(); x != 4 {
+		t.Error("expected 4 but got ", x)
+	}
+	if x := lines[1].End(); x != 9 {
+		t.Error("expected 9 but got ", x)
+	}
+}
+
+func TestLineFormats(t *testing.T) {
+	lines := lineTestDocument(t).Lines()
+	if x := lines[0].Header(); x != 1 {
+		t.Error("expected header 1 but got ", x)
+	}
+	if x := lines[1].List(); x != "bullet" {
+		t.Error("expected a bullet list but got ", x)
+	}
+	if x := lines[1].Indent(); x != 2 {
+		t.Error("expected indent 2 but got ", x)
+	}
+	if lines[2].Header() != 0 || lines[2].List() != "" || lines[2].Indent() != 0 || lines[2].Align() != "" ||
+		lines[2].CodeBlock() || lines[2].Blockquote() {
+		t.Errorf("expected no formats but got %+v\n", lines[2].Attributes)
+	}
+	if !lines[3].CodeBlock() {
+		t.Error("expected a code block")
+	}
+	if !lines[4].Blockquote() || lines[4].Align() != "center" {
+		t.Errorf("expected a centered blockquote but got %+v\n", lines[4].Attributes)
+	}
+}
+
+func TestDocumentLineAt(t *testing.T) {
+	doc := lineTestDocument(t)
+	cases := map[int]int{0: 0, 5: 0, 6: 6, 9: 6, 10: 10, 11: 11, 23: 18}
+	for index, start := range cases {
+		line, ok := doc.LineAt(index)
+		if !ok || line.Start != start {
+			t.Errorf("LineAt(%d): expected the line at %d but got %+v, %v\n", index, start, line, ok)
+		}
+	}
+	for _, index := range []int{-1, 24, 100} {
+		if line, ok := doc.LineAt(index); ok {
+			t.Errorf("LineAt(%d): expected no line but got %+v\n", index, line)
+		}
+	}
+}
+
+func TestDocumentLineRange(t *testing.T) {
+	doc := lineTestDocument(t)
+	starts := func(lines []Line) []int {
+		var ret []int
+		for _, l := range lines {
+			ret = append(ret, l.Start)
+		}
+		return ret
+	}
+	cases := []struct {
+		index, length int
+		expected      []int
+	}{
+		{0, 0, []int{0}},
+		{0, 6, []int{0}},
+		{0, 7, []int{0, 6}},
+		{5, 6, []int{0, 6, 10}},
+		{12, 100, []int{11, 18}},
+		{30, 1, nil},
+	}
+	for _, c := range cases {
+		if x := starts(doc.LineRange(c.index, c.length)); !reflect.DeepEqual(c.expected, x) {
+			t.Errorf("LineRange(%d, %d): expected %v but got %v\n", c.index, c.length, c.expected, x)
+		}
+	}
+}
+
+func TestLineChanges(t *testing.T) {
+	doc := lineTestDocument(t)
+	line, _ := doc.LineAt(7)
+
+	change := line.Format(AttributeMap{"list": "ordered"})
+	expected := New(nil).Retain(9, nil).Retain(1, AttributeMap{"list": "ordered"})
+	if !reflect.DeepEqual(expected, change) {
+		t.Errorf("expected %+v but got %+v\n", expected, change)
+	}
+	after := doc.Delta().Compose(*change)
+	if newDoc := newTestDocument(t, after); newDoc.Lines()[1].List() != "ordered" {
+		t.Errorf("expected an ordered list but got %+v\n", newDoc.Lines()[1])
+	}
+
+	change = line.Change(*New(nil).Retain(1, nil).Delete(1).Insert("N", nil))
+	expected = New(nil).Retain(7, nil).Insert("N", nil).Delete(1)
+	if !reflect.DeepEqual(expected, change) {
+		t.Errorf("expected %+v but got %+v\n", expected, change)
+	}
+	if x := doc.Delta().Compose(*change); !newTestDocument(t, x).Lines()[1].Delta.Equal(*New(nil).Insert("o", map[string]interface{}{"bold": true}).Insert("N", nil).Insert("e", map[string]interface{}{"bold": true})) {
+		t.Errorf("expected the line to be changed but got %+v\n", x)
+	}
+}
@@ -0,0 +1,74 @@
+package delta
+
+// listAliases maps list values that are not formats to the Quill 2 ones. Quill 1.3 and Quill 2 both
+// store "bullet", "ordered", "checked" and "unchecked", but their toolbar gives "check" to the
+// checklist button, and its list handler turns it into "unchecked"
+var listAliases = map[string]string{
+	"check": "unchecked",
+}
+
+// listValues are the list values Quill 2 knows about
+var listValues = map[string]bool{"bullet": true, "ordered": true, "checked": true, "unchecked": true}
+
+// NormalizeLists returns the change that fixes the list and indent formats of the Document,
+// without applying it:
+//   - list values from older Quill versions, like "check" or {"list": true}, become their Quill 2 value,
+//     and lines with a list value Quill doesn't know stop being list items
+//   - indent is removed from lines that are not list items, and when it's not a positive whole number
+//   - a list item is indented at most one level more than the list item before it, even with other
+//     lines in between, so a nested list can go on after a paragraph. The first list item of the
+//     Document is not indented
+//
+// The change is empty if the Document is already normalized
+func (doc *Document) NormalizeLists() *Delta {
+	change := New(nil)
+	offset := 0
+	// indent of the last list item, -1 before the first one
+	previous := -1
+	for _, line := range doc.Lines() {
+		attrs := line.Attributes.clone()
+		if attrs == nil {
+			attrs = make(AttributeMap)
+		}
+		list, isList := normalizeList(attrs["list"])
+		if _, found := attrs["list"]; found {
+			delete(attrs, "list")
+			if isList {
+				attrs["list"] = list
+			}
+		}
+
+		indent := intAttribute(attrs, "indent")
+		delete(attrs, "indent")
+		if isList {
+			indent = min(max(indent, 0), previous+1)
+			if indent > 0 {
+				attrs["indent"] = indent
+			}
+			previous = indent
+		}
+
+		// an indent written as another kind of number, like json.Number("1"), is not a difference
+		if diff := line.Attributes.Diff(attrs); len(diff) > 0 && line.End() < doc.GetLength() {
+			change.Retain(line.End()-offset, nil).Retain(1, diff)
+			offset = line.End() + 1
+		}
+	}
+	return change
+}
+
+// normalizeList returns the Quill 2 list value for v, or false if v is not a list
+func normalizeList(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		if listValues[v] {
+			return v, true
+		}
+		alias, ok := listAliases[v]
+		return alias, ok
+	case bool:
+		// Quill 0.20 had a boolean list format for numbered lists, and a separate one for bullets
+		return "ordered", v
+	}
+	return "", false
+}
@@ -0,0 +1,81 @@
+package delta
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeLists(t *testing.T) {
+	bullet := func(indent interface{}) map[string]interface{} {
+		attrs := map[string]interface{}{"list": "bullet"}
+		if indent != nil {
+			attrs["indent"] = indent
+		}
+		return attrs
+	}
+	doc := newTestDocument(t, New(nil).
+		Insert("paragraph", nil).Insert("\n", map[string]interface{}{"indent": 1}).
+		Insert("a", nil).Insert("\n", bullet(1)).
+		Insert("b", nil).Insert("\n", bullet(json.Number("1"))).
+		Insert("c", nil).Insert("\n", bullet(4)).
+		Insert("d", nil).Insert("\n", map[string]interface{}{"list": "ul", "indent": -1}).
+		Insert("e", nil).Insert("\n", map[string]interface{}{"list": "check"}).
+		Insert("f", nil).Insert("\n", map[string]interface{}{"list": "fancy", "indent": 2, "align": "right"}).
+		Insert("g", nil).Insert("\n", bullet("x")))
+	expected := New(nil).
+		Retain(9, nil).Retain(1, AttributeMap{"indent": nil}).
+		Retain(1, nil).Retain(1, AttributeMap{"indent": nil}).
+		Retain(3, nil).Retain(1, AttributeMap{"indent": 2}).
+		Retain(1, nil).Retain(1, AttributeMap{"list": nil, "indent": nil}).
+		Retain(1, nil).Retain(1, AttributeMap{"list": "unchecked"}).
+		Retain(1, nil).Retain(1, AttributeMap{"list": nil, "indent": nil}).
+		Retain(1, nil).Retain(1, AttributeMap{"indent": nil})
+	change := doc.NormalizeLists()
+	if !reflect.DeepEqual(expected, change) {
+		t.Errorf("expected %+v but got %+v\n", expected, change)
+	}
+
+	// normalizing twice changes nothing
+	normalized := newTestDocument(t, doc.Delta().Compose(*change))
+	if x := normalized.NormalizeLists(); len(x.Ops) != 0 {
+		t.Errorf("expected no change but got %+v\n", x)
+	}
+	if x := normalized.Lines()[6].Attributes; !reflect.DeepEqual(AttributeMap{"align": "right"}, x) {
+		t.Errorf("expected other formats to be kept but got %+v\n", x)
+	}
+}
+
+func TestNormalizeListsNested(t *testing.T) {
+	doc := newTestDocument(t, New(nil).
+		Insert("a", nil).Insert("\n", map[string]interface{}{"list": "ordered"}).
+		Insert("b", nil).Insert("\n", map[string]interface{}{"list": "ordered", "indent": 1}).
+		Insert("c", nil).Insert("\n", map[string]interface{}{"list": "bullet", "indent": 2}).
+		Insert("d", nil).Insert("\n", map[string]interface{}{"list": "ordered", "indent": float64(1)}).
+		Insert("e", nil).Insert("\n", map[string]interface{}{"list": true}))
+	expected := New(nil).Retain(9, nil).Retain(1, AttributeMap{"list": "ordered"})
+	if x := doc.NormalizeLists(); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestNormalizeListsAfterParagraph(t *testing.T) {
+	// a nested list that goes on after a paragraph keeps its indent, jumps are still fixed
+	doc := newTestDocument(t, New(nil).
+		Insert("a", nil).Insert("\n", map[string]interface{}{"list": "bullet"}).
+		Insert("b", nil).Insert("\n", map[string]interface{}{"list": "bullet", "indent": 1}).
+		Insert("paragraph\n", nil).
+		Insert("c", nil).Insert("\n", map[string]interface{}{"list": "bullet", "indent": 2}).
+		Insert("d", nil).Insert("\n", map[string]interface{}{"list": "bullet", "indent": 4}))
+	expected := New(nil).Retain(17, nil).Retain(1, AttributeMap{"indent": 3})
+	if x := doc.NormalizeLists(); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestNormalizeListsUnchanged(t *testing.T) {
+	doc := newTestDocument(t, New(nil).Insert("Hello\nWorld", nil))
+	if x := doc.NormalizeLists(); len(x.Ops) != 0 {
+		t.Errorf("expected no change but got %+v\n", x)
+	}
+}
@@ -0,0 +1,115 @@
+package delta
+
+// Assoc tells Mapping.Map which side a position sticks to when text is inserted right at it
+type Assoc int
+
+const (
+	// AssocLeft keeps the position before text inserted at it, like the end of a comment
+	AssocLeft Assoc = -1
+	// AssocRight moves the position after text inserted at it, like the start of a comment
+	AssocRight Assoc = 1
+)
+
+// mapRange is a part of the document that a Delta replaces:
+// oldSize units at start, in the document before the Delta, become newSize units
+type mapRange struct {
+	start   int
+	oldSize int
+	newSize int
+}
+
+// Mapping maps positions through one or more Deltas, applied one after the other.
+// Unlike TransformPosition, it tells you if the content next to a position was deleted
+type Mapping struct {
+	steps [][]mapRange
+}
+
+// NewMapping returns a Mapping through all the given Deltas, in order
+func NewMapping(deltas ...Delta) *Mapping {
+	m := &Mapping{}
+	for _, d := range deltas {
+		m.Append(d)
+	}
+	return m
+}
+
+// Append adds a Delta, applied after the ones already in the Mapping
+func (m *Mapping) Append(d Delta) *Mapping {
+	var ranges []mapRange
+	offset := 0
+	for _, op := range d.Ops {
+		length := OpsLength(op)
+		if !op.isInsert() && op.Delete == nil {
+			offset += length
+			continue
+		}
+		last := len(ranges) - 1
+		if last < 0 || ranges[last].start+ranges[last].oldSize != offset {
+			ranges = append(ranges, mapRange{start: offset})
+			last++
+		}
+		if op.Delete != nil {
+			ranges[last].oldSize += length
+			offset += length
+		} else {
+			ranges[last].newSize += length
+		}
+	}
+	m.steps = append(m.steps, ranges)
+	return m
+}
+
+// Map returns where pos ends up after all the Deltas of the Mapping.
+// assoc decides where pos goes when text is inserted right at it.
+// deleted is true if the content on the assoc side of pos was deleted by any of the Deltas,
+// a position inside deleted content ends up where the content was
+func (m *Mapping) Map(pos int, assoc Assoc) (int, bool) {
+	deleted := false
+	for _, ranges := range m.steps {
+		var del bool
+		pos, del = mapStep(ranges, pos, assoc)
+		deleted = deleted || del
+	}
+	return pos, deleted
+}
+
+// Invert returns a Mapping that maps positions from after the Deltas back to before them
+func (m *Mapping) Invert() *Mapping {
+	inverted := &Mapping{steps: make([][]mapRange, 0, len(m.steps))}
+	for i := len(m.steps) - 1; i >= 0; i-- {
+		ranges := make([]mapRange, 0, len(m.steps[i]))
+		diff := 0
+		for _, r := range m.steps[i] {
+			ranges = append(ranges, mapRange{start: r.start + diff, oldSize: r.newSize, newSize: r.oldSize})
+			diff += r.newSize - r.oldSize
+		}
+		inverted.steps = append(inverted.steps, ranges)
+	}
+	return inverted
+}
+
+// mapStep maps pos through the ranges of a single Delta
+func mapStep(ranges []mapRange, pos int, assoc Assoc) (int, bool) {
+	diff := 0
+	for _, r := range ranges {
+		if r.start > pos {
+			break
+		}
+		end := r.start + r.oldSize
+		if pos <= end {
+			side := assoc
+			if r.oldSize > 0 && pos == r.start {
+				side = AssocLeft
+			} else if r.oldSize > 0 && pos == end {
+				side = AssocRight
+			}
+			deleted := r.oldSize > 0 && !(pos == r.start && assoc == AssocLeft) && !(pos == end && assoc == AssocRight)
+			if side == AssocLeft {
+				return r.start + diff, deleted
+			}
+			return r.start + diff + r.newSize, deleted
+		}
+		diff += r.newSize - r.oldSize
+	}
+	return pos + diff, false
+}
@@ -0,0 +1,72 @@
+package delta
+
+import "testing"
+
+func expectMap(t *testing.T, m *Mapping, pos int, assoc Assoc, expected int, expectedDeleted bool) {
+	t.Helper()
+	x, deleted := m.Map(pos, assoc)
+	if x != expected || deleted != expectedDeleted {
+		t.Errorf("Map(%d, %d): expected %d, %v but got %d, %v\n", pos, assoc, expected, expectedDeleted, x, deleted)
+	}
+}
+
+func TestMappingInsert(t *testing.T) {
+	m := NewMapping(*New(nil).Retain(2, nil).Insert("abc", nil))
+	expectMap(t, m, 1, AssocRight, 1, false)
+	expectMap(t, m, 2, AssocLeft, 2, false)
+	expectMap(t, m, 2, AssocRight, 5, false)
+	expectMap(t, m, 3, AssocLeft, 6, false)
+}
+
+func TestMappingDelete(t *testing.T) {
+	m := NewMapping(*New(nil).Retain(2, nil).Delete(3))
+	expectMap(t, m, 2, AssocLeft, 2, false)
+	expectMap(t, m, 2, AssocRight, 2, true)
+	expectMap(t, m, 3, AssocLeft, 2, true)
+	expectMap(t, m, 5, AssocLeft, 2, true)
+	expectMap(t, m, 5, AssocRight, 2, false)
+	expectMap(t, m, 7, AssocRight, 4, false)
+}
+
+func TestMappingReplace(t *testing.T) {
+	m := NewMapping(*New(nil).Retain(1, nil).Insert("xy", nil).Delete(2))
+	expectMap(t, m, 1, AssocRight, 1, true)
+	expectMap(t, m, 2, AssocLeft, 1, true)
+	expectMap(t, m, 2, AssocRight, 3, true)
+	expectMap(t, m, 3, AssocRight, 3, false)
+	expectMap(t, m, 4, AssocLeft, 4, false)
+}
+
+func TestMappingFormatDoesNotMove(t *testing.T) {
+	m := NewMapping(*New(nil).Retain(3, map[string]interface{}{"bold": true}).RetainEmbed(Embed{"table": nil}, nil))
+	expectMap(t, m, 2, AssocLeft, 2, false)
+	expectMap(t, m, 5, AssocRight, 5, false)
+}
+
+func TestMappingSeveralDeltas(t *testing.T) {
+	a := New(nil).Insert("ab", nil)
+	b := New(nil).Retain(3, nil).Delete(2)
+	m := NewMapping(*a, *b)
+	expectMap(t, m, 0, AssocLeft, 0, false)
+	expectMap(t, m, 1, AssocLeft, 3, false)
+	expectMap(t, m, 2, AssocLeft, 3, true)
+	expectMap(t, m, 4, AssocLeft, 4, false)
+
+	// the same result as mapping through the composed Delta
+	composed := NewMapping(*a.Compose(*b))
+	expectMap(t, composed, 1, AssocLeft, 3, false)
+	expectMap(t, composed, 2, AssocLeft, 3, true)
+}
+
+func TestMappingInvert(t *testing.T) {
+	m := NewMapping(*New(nil).Retain(2, nil).Insert("abc", nil), *New(nil).Delete(1))
+	inverted := m.Invert()
+	for _, pos := range []int{0, 2, 3, 5} {
+		x, _ := m.Map(pos, AssocLeft)
+		if back, _ := inverted.Map(x, AssocLeft); back != pos {
+			t.Errorf("expected %d to map back to itself but got %d\n", pos, back)
+		}
+	}
+	expectMap(t, inverted, 3, AssocLeft, 2, true)
+	expectMap(t, inverted, 1, AssocLeft, 2, false)
+}
@@ -1,97 +1,54 @@
 package delta
 
-// AttrCompose takes two attributes maps and composes (combine) them
+// AttrCompose takes two attributes maps and composes (combine) them, see AttributeMap.Compose
 func AttrCompose(a, b map[string]interface{}, keepNil bool) map[string]interface{} {
-	attributes := make(map[string]interface{})
-	if b != nil {
-		attributes = b
-	}
-
-	for k := range a {
-		aa, aFound := a[k]
-		bb, bFound := b[k]
-		if !keepNil && bb == nil { // a nil check to match the null special case in quilljs
-			delete(attributes, k)
-		}
+	return AttributeMap(a).Compose(b, keepNil)
+}
 
-		if aFound && !bFound {
-			attributes[k] = aa
-		}
-	}
-	// clean up any nil attributes that were on b but not on a
-	for k, v := range attributes {
-		if !keepNil && v == nil {
-			delete(attributes, k)
-		}
-	}
-	if len(attributes) > 0 {
-		return attributes
-	}
-	return nil
+// AttrComposeDeep is like AttrCompose but merges object values key by key.
+// Composing deltas never uses it, see AttributeMap.ComposeDeep
+func AttrComposeDeep(a, b map[string]interface{}, keepNil bool) map[string]interface{} {
+	return AttributeMap(a).ComposeDeep(b, keepNil)
 }
 
-// AttrDiff returns the diff between two maps of attributes
+// AttrDiff returns the diff between two maps of attributes, see AttributeMap.Diff
 func AttrDiff(a, b map[string]interface{}) map[string]interface{} {
-	keys := make([]string, len(a)+len(b))
-	attributes := make(map[string]interface{})
-
-	if a == nil {
-		a = make(map[string]interface{})
-	}
-
-	if b == nil {
-		b = make(map[string]interface{})
-	}
-	for k := range a {
-		keys = append(keys, k)
-	}
-	for kk := range b {
-		keys = append(keys, kk)
-	}
+	return AttributeMap(a).Diff(b)
+}
 
-	for _, v := range keys {
-		if a[v] != b[v] {
-			bb, bFound := b[v]
-			if !bFound {
-				attributes[v] = nil
-			} else {
-				attributes[v] = bb
-			}
-		}
-	}
-	if len(attributes) > 0 {
-		return attributes
-	}
-	return nil
+// AttrInvert returns the attributes that undo attr when applied on top of base, see AttributeMap.Invert
+func AttrInvert(attr, base map[string]interface{}) map[string]interface{} {
+	return AttributeMap(attr).Invert(base)
 }
 
-// AttrTransform is used in Detal.transform(), hard to really explain
+// AttrTransform is used in Detal.transform(), see AttributeMap.Transform
 func AttrTransform(a, b map[string]interface{}, priority bool) map[string]interface{} {
-	if a == nil {
-		return b
-	}
-	if b == nil {
-		return nil
-	}
-	// b simply overwrites us without priority
-	if !priority {
-		return b
-	}
+	return AttributeMap(a).Transform(b, priority)
+}
 
-	attributes := make(map[string]interface{})
+// OpType is the kind of an Op
+type OpType string
 
-	for k := range b {
-		_, aOk := a[k]
-		if !aOk {
-			// nil is a valid value
-			attributes[k] = b[k]
-		}
-	}
+const (
+	// OpInsert inserts text or an embed
+	OpInsert OpType = "insert"
+	// OpRetain keeps characters or an embed, maybe changing their attributes
+	OpRetain OpType = "retain"
+	// OpDelete removes characters
+	OpDelete OpType = "delete"
+)
 
-	if len(attributes) > 0 {
-		return attributes
-	}
-	return nil
+// Type returns the kind of the Op, or an empty OpType for an Op that does nothing
+func (o Op) Type() OpType {
+	switch {
+	case o.Delete != nil:
+		return OpDelete
+	case o.isRetain():
+		return OpRetain
+	case o.isInsert():
+		return OpInsert
+	}
+	return ""
 }
 
 // OpsIterator returns an Iterator wrapping the ops
@@ -99,7 +56,8 @@ func OpsIterator(ops []Op) Iterator {
 	return NewIterator(ops)
 }
 
-// OpsLength returns the length of the string insert, or the numeric value of Delete or Retain
+// OpsLength returns the length of the string insert in the current LengthMode, 1 for an embed or a retain embed,
+// or the numeric value of Delete or Retain
 func OpsLength(op Op) int {
 	if op.Delete != nil {
 		return *op.Delete
@@ -107,8 +65,11 @@ func OpsLength(op Op) int {
 	if op.Retain != nil {
 		return *op.Retain
 	}
+	if op.InsertEmbed != nil || op.RetainEmbed != nil {
+		return 1
+	}
 	if op.Insert != nil {
-		return len(op.Insert)
+		return textLength(op.Insert)
 	}
 
 	return 1
@@ -192,6 +192,123 @@ func TestAttrDiffOverrideFormat(t *testing.T) {
 	}
 }
 
+func TestAttrInvertAttrNil(t *testing.T) {
+	base := make(map[string]interface{})
+	base["bold"] = true
+
+	if AttrInvert(nil, base) != nil {
+		t.Errorf("failed to invert attr map, got: %+v\n", AttrInvert(nil, base))
+	}
+}
+func TestAttrInvertBaseNil(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+
+	expected := make(map[string]interface{})
+	expected["bold"] = nil
+
+	if !reflect.DeepEqual(expected, AttrInvert(attr, nil)) {
+		t.Errorf("failed to invert attr map, got: %+v\n", AttrInvert(attr, nil))
+	}
+}
+func TestAttrInvertBothNil(t *testing.T) {
+	if AttrInvert(nil, nil) != nil {
+		t.Errorf("failed to invert attr map, got: %+v\n", AttrInvert(nil, nil))
+	}
+}
+func TestAttrInvertMerge(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	base := make(map[string]interface{})
+	base["italic"] = true
+
+	expected := make(map[string]interface{})
+	expected["bold"] = nil
+
+	if !reflect.DeepEqual(expected, AttrInvert(attr, base)) {
+		t.Errorf("failed to invert attr map, got: %+v\n", AttrInvert(attr, base))
+	}
+}
+func TestAttrInvertNil(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = nil
+	base := make(map[string]interface{})
+	base["bold"] = true
+
+	expected := make(map[string]interface{})
+	expected["bold"] = true
+
+	if !reflect.DeepEqual(expected, AttrInvert(attr, base)) {
+		t.Errorf("failed to invert attr map, got: %+v\n", AttrInvert(attr, base))
+	}
+}
+func TestAttrInvertReplace(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["color"] = "red"
+	base := make(map[string]interface{})
+	base["color"] = "blue"
+
+	if !reflect.DeepEqual(base, AttrInvert(attr, base)) {
+		t.Errorf("failed to invert attr map, got: %+v\n", AttrInvert(attr, base))
+	}
+}
+func TestAttrInvertNoop(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["color"] = "red"
+	base := make(map[string]interface{})
+	base["color"] = "red"
+
+	if AttrInvert(attr, base) != nil {
+		t.Errorf("failed to invert attr map, got: %+v\n", AttrInvert(attr, base))
+	}
+}
+func TestAttrInvertCombined(t *testing.T) {
+	attr := make(map[string]interface{})
+	attr["bold"] = true
+	attr["italic"] = nil
+	attr["color"] = "red"
+	attr["size"] = "12px"
+	base := make(map[string]interface{})
+	base["font"] = "serif"
+	base["italic"] = true
+	base["color"] = "blue"
+	base["size"] = "12px"
+
+	expected := make(map[string]interface{})
+	expected["bold"] = nil
+	expected["italic"] = true
+	expected["color"] = "blue"
+
+	if !reflect.DeepEqual(expected, AttrInvert(attr, base)) {
+		t.Errorf("failed to invert attr map, got: %+v\n", AttrInvert(attr, base))
+	}
+}
+
+func TestAttrDiffObjectValues(t *testing.T) {
+	format := make(map[string]interface{})
+	format["font"] = map[string]interface{}{"family": "Helvetica", "size": float64(15)}
+
+	other := make(map[string]interface{})
+	other["font"] = map[string]interface{}{"family": "Helvetica", "size": 15}
+
+	if AttrDiff(format, other) != nil {
+		t.Errorf("failed to diff attr map, got: %+v\n", AttrDiff(format, other))
+	}
+}
+func TestAttrComposeDeep(t *testing.T) {
+	attr1 := make(map[string]interface{})
+	attr1["style"] = map[string]interface{}{"color": "red"}
+	attr2 := make(map[string]interface{})
+	attr2["style"] = map[string]interface{}{"font": "serif"}
+
+	ret := make(map[string]interface{})
+	ret["style"] = map[string]interface{}{"color": "red", "font": "serif"}
+
+	if !reflect.DeepEqual(ret, AttrComposeDeep(attr1, attr2, false)) {
+		t.Errorf("failed to compose attr map, got: %+v\n", AttrComposeDeep(attr1, attr2, false))
+	}
+}
+
 func TestAttrTransformLeftNil(t *testing.T) {
 	left := make(map[string]interface{})
 	left["bold"] = true
@@ -291,3 +408,12 @@ func TestAttrLengthInsert(t *testing.T) {
 		t.Error("failed to get length 4 for insert")
 	}
 }
+func TestAttrLengthEmbed(t *testing.T) {
+	r := OpsLength(Op{
+		InsertEmbed: Embed{"image": "http://quilljs.com"},
+	})
+
+	if r != 1 {
+		t.Error("failed to get length 1 for embed")
+	}
+}
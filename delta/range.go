@@ -0,0 +1,26 @@
+package delta
+
+// Range is a selection in a document, like the one Quill's getSelection returns.
+// A Length of 0 is a cursor
+type Range struct {
+	Index  int `json:"index"`
+	Length int `json:"length"`
+}
+
+// End returns the index right after the last character of the range
+func (r Range) End() int {
+	return r.Index + r.Length
+}
+
+// TransformRange returns where r ends up after applying the Delta, see TransformPosition for priority.
+// A range that is fully deleted collapses into a cursor where the deleted text was
+func (d *Delta) TransformRange(r Range, priority bool) Range {
+	positions := d.TransformPositions([]int{r.Index, r.End()}, priority)
+	return Range{Index: positions[0], Length: positions[1] - positions[0]}
+}
+
+// TransformPositions is like calling TransformPosition for every index in positions,
+// but it only walks the Delta once. The returned slice keeps the order of positions
+func (d *Delta) TransformPositions(positions []int, priority bool) []int {
+	return d.seq().TransformPositions(positions, priority)
+}
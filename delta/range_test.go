@@ -0,0 +1,129 @@
+package delta
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransformRangeInsertBefore(t *testing.T) {
+	delta := New(nil).Retain(1, nil).Insert("ab", nil)
+	expected := Range{Index: 4, Length: 3}
+	if x := delta.TransformRange(Range{Index: 2, Length: 3}, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestTransformRangeInsertInside(t *testing.T) {
+	delta := New(nil).Retain(3, nil).Insert("ab", nil)
+	expected := Range{Index: 2, Length: 5}
+	if x := delta.TransformRange(Range{Index: 2, Length: 3}, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestTransformRangeInsertAtEdges(t *testing.T) {
+	delta := New(nil).Retain(2, nil).Insert("a", nil).Retain(3, nil).Insert("b", nil)
+	expected := Range{Index: 2, Length: 4}
+	if x := delta.TransformRange(Range{Index: 2, Length: 3}, true); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	expected = Range{Index: 3, Length: 4}
+	if x := delta.TransformRange(Range{Index: 2, Length: 3}, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestTransformRangeDeleteOverlap(t *testing.T) {
+	delta := New(nil).Retain(1, nil).Delete(3)
+	expected := Range{Index: 1, Length: 2}
+	if x := delta.TransformRange(Range{Index: 2, Length: 4}, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestTransformRangeDeleted(t *testing.T) {
+	delta := New(nil).Retain(1, nil).Delete(5)
+	expected := Range{Index: 1, Length: 0}
+	if x := delta.TransformRange(Range{Index: 2, Length: 3}, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+
+	// replacing the range with new text leaves the cursor after it
+	delta = New(nil).Retain(2, nil).Delete(3).Insert("xyz", nil)
+	expected = Range{Index: 5, Length: 0}
+	if x := delta.TransformRange(Range{Index: 2, Length: 3}, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+// transformPosition is the single position walk TransformPositions replaced, to check it against
+func transformPosition(d *Delta, index int, priority bool) int {
+	iter := NewIterator(d.Ops)
+	offset := 0
+	for iter.HasNext() && offset <= index {
+		length := iter.PeekLength()
+		nextType := iter.PeekType()
+		iter.NextOp()
+		if nextType == OpDelete {
+			index -= min(length, index-offset)
+			continue
+		} else if nextType == OpInsert && (offset < index || !priority) {
+			index += length
+		}
+		offset += length
+	}
+	return index
+}
+
+func TestTransformRangeDeleteNextToRange(t *testing.T) {
+	delta := New(nil).Retain(1, nil).Delete(1).Retain(2, nil).Insert("z", nil)
+	expected := Range{Index: 1, Length: 2}
+	if x := delta.TransformRange(Range{Index: 2, Length: 2}, true); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+
+	delta = New(nil).Retain(4, nil).Delete(1)
+	expected = Range{Index: 2, Length: 2}
+	if x := delta.TransformRange(Range{Index: 2, Length: 2}, true); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestTransformPositions(t *testing.T) {
+	delta := New(nil).Retain(2, nil).Insert("ab", nil).Delete(2).Retain(1, nil).Insert("c", nil)
+	positions := []int{7, 0, 3, 2, 5, 4}
+	expected := []int{8, 0, 4, 2, 5, 4}
+	if x := delta.TransformPositions(positions, true); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	if !reflect.DeepEqual([]int{7, 0, 3, 2, 5, 4}, positions) {
+		t.Error("TransformPositions changed its input ", positions)
+	}
+}
+
+func TestTransformPositionsMatchesSinglePositions(t *testing.T) {
+	deltas := []*Delta{
+		New(nil).Retain(2, nil).Insert("ab", nil).Delete(2).Retain(1, nil).Insert("c", nil),
+		New(nil).Insert("x", nil).Delete(3).Insert("y", nil).Retain(2, AttributeMap{"bold": true}).Delete(1),
+		New(nil).Delete(1).Retain(1, nil).Delete(2).Insert("xyz", nil),
+	}
+	positions := []int{9, 0, 1, 2, 3, 4, 5, 6, 7, 8, 3, 0}
+	for _, delta := range deltas {
+		for _, priority := range []bool{true, false} {
+			expected := make([]int, len(positions))
+			for i, p := range positions {
+				expected[i] = transformPosition(delta, p, priority)
+			}
+			if x := delta.TransformPositions(positions, priority); !reflect.DeepEqual(expected, x) {
+				t.Errorf("%+v priority %v: expected %+v but got %+v\n", delta.Ops, priority, expected, x)
+			}
+		}
+	}
+}
+
+func TestTransformPositionsEmpty(t *testing.T) {
+	delta := New(nil).Insert("a", nil)
+	if x := delta.TransformPositions(nil, false); len(x) != 0 {
+		t.Error("expected no positions but got ", x)
+	}
+}
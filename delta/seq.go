@@ -0,0 +1,572 @@
+package delta
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// ErrNoChanger is used when a Seq has a retain with a Change but its Content doesn't implement Changer
+var ErrNoChanger = errors.New("content does not implement Changer")
+
+// Content is what a Seq inserts. The OT algorithms only need to know how long it is
+// and how to split it, so it can be text, a list of blocks or anything else
+type Content[T any] interface {
+	// Len returns the length of the content, inserts must have a positive length
+	Len() int
+	// Slice returns the part of the content between i and j, with 0 <= i < j <= Len()
+	Slice(i, j int) T
+}
+
+// Merger can be implemented by a Content so Push merges consecutive inserts with the same attributes.
+// Merge returns false if the two can't be merged, like two embeds
+type Merger[T any] interface {
+	Merge(other T) (T, bool)
+}
+
+// Changer can be implemented by a Content whose retains can change the content they retain,
+// like the retain embeds of Quill 2, see SeqOp.Change. The Seq methods panic with an error that
+// wraps ErrNoChanger if they find a Change in a Seq whose Content doesn't implement it
+type Changer[T any] interface {
+	// ComposeChange returns the receiver, inserted content or a change, with change applied on top.
+	// keepNil is true when the receiver is a change, so removals have to be kept
+	ComposeChange(change T, keepNil bool) T
+	// TransformChange returns change transformed against the receiver, a concurrent change,
+	// or false if it can't, which keeps change as is
+	TransformChange(change T, priority bool) (T, bool)
+	// InvertChange returns the change that undoes the receiver, a change, when applied on top of base
+	InvertChange(base T) T
+}
+
+// changer returns t as a Changer, it panics if T doesn't implement it
+func changer[T any](t T) Changer[T] {
+	c, ok := any(t).(Changer[T])
+	if !ok {
+		panic(fmt.Errorf("%w: %T", ErrNoChanger, t))
+	}
+	return c
+}
+
+// inPlaceMerger is implemented by Contents that can merge into themselves without copying,
+// which Seq does when the content was allocated by an earlier merge of the same Seq
+type inPlaceMerger[T any] interface {
+	mergeInPlace(other T) (T, bool)
+}
+
+// SeqOp is an Op of a Seq. It's a delete if Delete is positive, a retain if Retain is positive,
+// and an insert of Insert otherwise.
+// A retain of 1 can also change the content it retains with Change, see Changer
+type SeqOp[T Content[T]] struct {
+	Insert     T
+	Retain     int
+	Change     *T
+	Delete     int
+	Attributes AttributeMap
+}
+
+// IsInsert tells you if the SeqOp inserts content
+func (o SeqOp[T]) IsInsert() bool {
+	return o.Retain <= 0 && o.Delete <= 0
+}
+
+// Len returns the length of the SeqOp
+func (o SeqOp[T]) Len() int {
+	if o.Delete > 0 {
+		return o.Delete
+	}
+	if o.Retain > 0 {
+		return o.Retain
+	}
+	return o.Insert.Len()
+}
+
+// Seq is a Delta over any kind of Content. It holds the OT algebra of the package: Delta is a Seq[Text],
+// and its Compose, Transform, Invert and Slice run the methods of Seq.
+// Attributes are copied like in Delta, but Content values are shared, so treat them as immutable
+type Seq[T Content[T]] struct {
+	Ops []SeqOp[T]
+}
+
+// NewSeq creates a new Seq with the given ops
+func NewSeq[T Content[T]](ops []SeqOp[T]) *Seq[T] {
+	return &Seq[T]{Ops: ops}
+}
+
+// Insert adds content with the given attributes, content with no length is ignored
+func (s *Seq[T]) Insert(content T, attrs AttributeMap) *Seq[T] {
+	if content.Len() <= 0 {
+		return s
+	}
+	return s.Push(SeqOp[T]{Insert: content, Attributes: attrs})
+}
+
+// Retain keeps n items and applies the attrs if present
+func (s *Seq[T]) Retain(n int, attrs AttributeMap) *Seq[T] {
+	if n <= 0 {
+		return s
+	}
+	return s.Push(SeqOp[T]{Retain: n, Attributes: attrs})
+}
+
+// Delete removes n items
+func (s *Seq[T]) Delete(n int) *Seq[T] {
+	if n <= 0 {
+		return s
+	}
+	return s.Push(SeqOp[T]{Delete: n})
+}
+
+// Push adds newOp to the Seq, merging it with the last op when possible, like Delta.Push
+func (s *Seq[T]) Push(newOp SeqOp[T]) *Seq[T] {
+	s.push(newOp, false)
+	return s
+}
+
+// push adds newOp and tells if the content of the last op is now owned by the Seq, because it was
+// allocated by a merge. lastOwned is what the previous call returned, when it's true the next merge
+// can append to the content in place instead of copying it, so building long inserts stays linear
+func (s *Seq[T]) push(newOp SeqOp[T], lastOwned bool) bool {
+	newOp.Attributes = newOp.Attributes.clone().orNil()
+	idx := len(s.Ops)
+	if idx == 0 {
+		s.Ops = append(s.Ops, newOp)
+		return false
+	}
+	lastOp := s.Ops[idx-1]
+	if newOp.Delete > 0 && lastOp.Delete > 0 {
+		s.Ops[idx-1] = SeqOp[T]{Delete: lastOp.Delete + newOp.Delete}
+		return false
+	}
+	// Since it does not matter if we insert before or after deleting at the same index,
+	// always prefer to insert first
+	if lastOp.Delete > 0 && newOp.IsInsert() {
+		idx--
+		if idx == 0 {
+			s.Ops = append([]SeqOp[T]{newOp}, s.Ops...)
+			return false
+		}
+		lastOp = s.Ops[idx-1]
+		lastOwned = false
+	}
+	if newOp.Attributes.Equal(lastOp.Attributes) {
+		if newOp.IsInsert() && lastOp.IsInsert() {
+			merged, ok := lastOp.Insert, false
+			if inPlace, isInPlace := any(lastOp.Insert).(inPlaceMerger[T]); isInPlace && lastOwned {
+				merged, ok = inPlace.mergeInPlace(newOp.Insert)
+			} else if merger, isMerger := any(lastOp.Insert).(Merger[T]); isMerger {
+				merged, ok = merger.Merge(newOp.Insert)
+			}
+			if ok {
+				s.Ops[idx-1] = SeqOp[T]{Insert: merged, Attributes: newOp.Attributes}
+				return idx == len(s.Ops)
+			}
+		} else if newOp.Retain > 0 && lastOp.Retain > 0 && newOp.Change == nil && lastOp.Change == nil {
+			s.Ops[idx-1] = SeqOp[T]{Retain: lastOp.Retain + newOp.Retain, Attributes: newOp.Attributes}
+			return false
+		}
+	}
+	s.Ops = slices.Insert(s.Ops, idx, newOp)
+	return false
+}
+
+// Chop removes the last retain operation if it doesn't have any attributes
+func (s *Seq[T]) Chop() *Seq[T] {
+	if x := len(s.Ops); x > 0 && s.Ops[x-1].Retain > 0 && s.Ops[x-1].Change == nil && s.Ops[x-1].Attributes == nil {
+		s.Ops = s.Ops[:x-1]
+	}
+	return s
+}
+
+// Length returns the sum of the lengths of all the ops in the Seq
+func (s *Seq[T]) Length() int {
+	length := 0
+	for _, op := range s.Ops {
+		length += op.Len()
+	}
+	return length
+}
+
+// ChangeLength returns how much longer (or shorter if negative) a document gets after applying the Seq
+func (s *Seq[T]) ChangeLength() int {
+	length := 0
+	for _, op := range s.Ops {
+		if op.IsInsert() {
+			length += op.Len()
+		} else if op.Delete > 0 {
+			length -= op.Delete
+		}
+	}
+	return length
+}
+
+// Slice returns the ops between start and end, splitting the ops at the edges if needed
+func (s *Seq[T]) Slice(start, end int) *Seq[T] {
+	var ops []SeqOp[T]
+	iter := NewSeqIterator(s.Ops)
+	index := 0
+	for index < end && iter.HasNext() {
+		var nextOp SeqOp[T]
+		if index < start {
+			nextOp = iter.Next(start - index)
+		} else {
+			nextOp = iter.Next(end - index)
+			nextOp.Attributes = nextOp.Attributes.clone()
+			ops = append(ops, nextOp)
+		}
+		index += nextOp.Len()
+	}
+	return NewSeq(ops)
+}
+
+// Compose returns a Seq that is equivalent to applying s, followed by other.
+// Changes to the same content are composed by its Changer
+func (s *Seq[T]) Compose(other Seq[T]) *Seq[T] {
+	thisIter := NewSeqIterator(s.Ops)
+	otherIter := NewSeqIterator(other.Ops)
+	seq := NewSeq[T](nil)
+	owned := false
+	for thisIter.HasNext() || otherIter.HasNext() {
+		if otherIter.PeekType() == OpInsert {
+			owned = seq.push(otherIter.NextOp(), owned)
+		} else if thisIter.PeekType() == OpDelete {
+			owned = seq.push(thisIter.NextOp(), owned)
+		} else {
+			length := min(thisIter.PeekLength(), otherIter.PeekLength())
+			thisOp := thisIter.Next(length)
+			otherOp := otherIter.Next(length)
+			if otherOp.Retain > 0 {
+				newOp := SeqOp[T]{Insert: thisOp.Insert}
+				switch {
+				case thisOp.Change != nil && otherOp.Change != nil:
+					// both sides change the same content
+					change := changer(*thisOp.Change).ComposeChange(*otherOp.Change, true)
+					newOp = SeqOp[T]{Retain: length, Change: &change}
+				case thisOp.Retain > 0:
+					newOp = SeqOp[T]{Retain: length, Change: thisOp.Change}
+					if otherOp.Change != nil {
+						newOp.Change = otherOp.Change
+					}
+				case otherOp.Change != nil:
+					newOp.Insert = changer(thisOp.Insert).ComposeChange(*otherOp.Change, false)
+				}
+				// Preserve null when composing with a retain, otherwise remove it for inserts
+				newOp.Attributes = thisOp.Attributes.Compose(otherOp.Attributes, thisOp.Retain > 0 && thisOp.Change == nil)
+				owned = seq.push(newOp, owned)
+				// Other op should be delete, we could be an insert or retain
+				// Insert + delete cancels out
+			} else if otherOp.Delete > 0 && thisOp.Retain > 0 {
+				owned = seq.push(otherOp, owned)
+			}
+		}
+	}
+	return seq.Chop()
+}
+
+// Transform returns other transformed against s, see Delta.Transform.
+// Concurrent changes to the same content are transformed by its Changer
+func (s *Seq[T]) Transform(other Seq[T], priority bool) *Seq[T] {
+	thisIter := NewSeqIterator(s.Ops)
+	otherIter := NewSeqIterator(other.Ops)
+	seq := NewSeq[T](nil)
+	owned := false
+	for thisIter.HasNext() || otherIter.HasNext() {
+		if thisIter.PeekType() == OpInsert && (priority || otherIter.PeekType() != OpInsert) {
+			owned = seq.push(SeqOp[T]{Retain: thisIter.NextOp().Len()}, owned)
+		} else if otherIter.PeekType() == OpInsert {
+			owned = seq.push(otherIter.NextOp(), owned)
+		} else {
+			length := min(thisIter.PeekLength(), otherIter.PeekLength())
+			thisOp := thisIter.Next(length)
+			otherOp := otherIter.Next(length)
+			attributes := thisOp.Attributes.Transform(otherOp.Attributes, priority)
+			if thisOp.Delete > 0 {
+				// Our delete either makes their delete redundant or removes their retain
+				continue
+			} else if otherOp.Delete > 0 {
+				owned = seq.push(otherOp, owned)
+			} else if otherOp.Change != nil {
+				// We keep their change, transformed against ours if we have one
+				change := *otherOp.Change
+				if thisOp.Change != nil {
+					if transformed, ok := changer(*thisOp.Change).TransformChange(change, priority); ok {
+						change = transformed
+					}
+				}
+				owned = seq.push(SeqOp[T]{Retain: length, Change: &change, Attributes: attributes}, owned)
+			} else {
+				// We retain either their retain or insert
+				owned = seq.push(SeqOp[T]{Retain: length, Attributes: attributes}, owned)
+			}
+		}
+	}
+	return seq.Chop()
+}
+
+// TransformPosition returns the new index after applying the Seq.
+// With priority, an insert right at index goes after it, otherwise index moves past the insert
+func (s *Seq[T]) TransformPosition(index int, priority bool) int {
+	return s.TransformPositions([]int{index}, priority)[0]
+}
+
+// TransformPositions is like calling TransformPosition for every index in positions,
+// but it only walks the Seq once. The returned slice keeps the order of positions
+func (s *Seq[T]) TransformPositions(positions []int, priority bool) []int {
+	ret := append([]int(nil), positions...)
+	// order has the indexes of positions from the smallest to the largest position,
+	// the first ones are done as soon as the Seq moves past them
+	order := make([]int, len(positions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return positions[order[a]] < positions[order[b]]
+	})
+
+	offset := 0
+	for _, op := range s.Ops {
+		for len(order) > 0 && offset > ret[order[0]] {
+			order = order[1:]
+		}
+		if len(order) == 0 {
+			break
+		}
+		length := op.Len()
+		for _, i := range order {
+			if op.Delete > 0 {
+				ret[i] -= min(length, ret[i]-offset)
+			} else if op.IsInsert() && (offset < ret[i] || !priority) {
+				ret[i] += length
+			}
+		}
+		if op.Delete <= 0 {
+			offset += length
+		}
+	}
+	return ret
+}
+
+// Invert returns a Seq that undoes s when applied on top of base, the document s was applied to.
+// Changes are inverted by their Changer
+func (s *Seq[T]) Invert(base Seq[T]) *Seq[T] {
+	baseIter := NewSeqIterator(base.Ops)
+	seq := NewSeq[T](nil)
+	owned := false
+	for _, op := range s.Ops {
+		length := op.Len()
+		if op.IsInsert() {
+			owned = seq.push(SeqOp[T]{Delete: length}, owned)
+			continue
+		}
+		if op.Change != nil {
+			baseOp := baseIter.Next(1)
+			change := changer(*op.Change).InvertChange(baseOp.Insert)
+			owned = seq.push(SeqOp[T]{Retain: 1, Change: &change, Attributes: op.Attributes.Invert(baseOp.Attributes)}, owned)
+			continue
+		}
+		if op.Retain > 0 && len(op.Attributes) == 0 {
+			owned = seq.push(SeqOp[T]{Retain: length}, owned)
+		}
+		// walk the part of base that we deleted or formatted
+		for length > 0 && baseIter.HasNext() {
+			baseOp := baseIter.Next(length)
+			baseLength := baseOp.Len()
+			if op.Delete > 0 {
+				owned = seq.push(baseOp, owned)
+			} else if len(op.Attributes) > 0 {
+				owned = seq.push(SeqOp[T]{Retain: baseLength, Attributes: op.Attributes.Invert(baseOp.Attributes)}, owned)
+			}
+			length -= baseLength
+		}
+	}
+	return seq.Chop()
+}
+
+// SeqIterator walks the ops of a Seq, splitting them as needed, like Iterator does for a Delta
+type SeqIterator[T Content[T]] struct {
+	Ops    []SeqOp[T]
+	Index  int
+	Offset int
+}
+
+// NewSeqIterator returns a SeqIterator over ops
+func NewSeqIterator[T Content[T]](ops []SeqOp[T]) *SeqIterator[T] {
+	return &SeqIterator[T]{Ops: ops}
+}
+
+// HasNext returns true if we have more ops
+func (x *SeqIterator[T]) HasNext() bool {
+	return x.Index < len(x.Ops)
+}
+
+// Peek returns the current op without advancing, or false if there are no more ops
+func (x *SeqIterator[T]) Peek() (SeqOp[T], bool) {
+	if !x.HasNext() {
+		return SeqOp[T]{}, false
+	}
+	return x.Ops[x.Index], true
+}
+
+// PeekType tells you the type of the current op, OpRetain past the last op
+func (x *SeqIterator[T]) PeekType() OpType {
+	op, ok := x.Peek()
+	switch {
+	case !ok || op.Retain > 0:
+		return OpRetain
+	case op.Delete > 0:
+		return OpDelete
+	}
+	return OpInsert
+}
+
+// NextOp returns what is left of the current op and moves to the next one
+func (x *SeqIterator[T]) NextOp() SeqOp[T] {
+	return x.Next(math.MaxInt64)
+}
+
+// PeekLength returns the length left in the current op
+func (x *SeqIterator[T]) PeekLength() int {
+	if !x.HasNext() {
+		return math.MaxInt64
+	}
+	return x.Ops[x.Index].Len() - x.Offset
+}
+
+// Next returns up to length of the current op and moves past it.
+// The returned op shares its attributes and content with the iterated ops.
+// Past the last op it returns a retain of math.MaxInt64, the rest of the content that a Seq
+// implicitly retains, so Compose and Transform can walk one side after the other ran out
+func (x *SeqIterator[T]) Next(length int) SeqOp[T] {
+	if !x.HasNext() {
+		return SeqOp[T]{Retain: math.MaxInt64}
+	}
+	nextOp := x.Ops[x.Index]
+	offset := x.Offset
+	opLength := nextOp.Len()
+	length = min(length, opLength-offset)
+	insert := nextOp.Insert
+	if nextOp.IsInsert() && (offset > 0 || length < opLength) {
+		// slice before moving, so a Content that panics leaves the iterator untouched
+		insert = insert.Slice(offset, offset+length)
+	}
+	if offset+length >= opLength {
+		x.Index++
+		x.Offset = 0
+	} else {
+		x.Offset += length
+	}
+	switch {
+	case nextOp.Delete > 0:
+		return SeqOp[T]{Delete: length}
+	case nextOp.Retain > 0:
+		return SeqOp[T]{Retain: length, Change: nextOp.Change, Attributes: nextOp.Attributes}
+	}
+	return SeqOp[T]{Insert: insert, Attributes: nextOp.Attributes}
+}
+
+// Text is the Content of a text Delta: either runes or a single embed, measured in the current LengthMode
+type Text struct {
+	Runes []rune
+	Embed Embed
+}
+
+// Len returns 1 for an embed and the length of the runes otherwise
+func (t Text) Len() int {
+	if t.Embed != nil {
+		return 1
+	}
+	return textLength(t.Runes)
+}
+
+// Slice returns the runes between i and j, it panics with ErrSurrogateSplit if that would split a character
+func (t Text) Slice(i, j int) Text {
+	if t.Embed != nil {
+		return t
+	}
+	start := runeIndex(t.Runes, 0, 0, i)
+	end := runeIndex(t.Runes, start, i, j)
+	return Text{Runes: t.Runes[start:end:end]}
+}
+
+// Merge joins two texts, embeds are never merged
+func (t Text) Merge(other Text) (Text, bool) {
+	if t.Embed != nil || other.Embed != nil {
+		return t, false
+	}
+	return Text{Runes: append(t.Runes[:len(t.Runes):len(t.Runes)], other.Runes...)}, true
+}
+
+// mergeInPlace is Merge for text the Seq owns, it appends to the runes of t
+func (t Text) mergeInPlace(other Text) (Text, bool) {
+	if t.Embed != nil || other.Embed != nil {
+		return t, false
+	}
+	return Text{Runes: append(t.Runes, other.Runes...)}, true
+}
+
+// ComposeChange applies the change of an embed to t, with the EmbedHandler registered for the type of the embed.
+// Like Delta.Compose, it panics if there is no handler or if t is not an embed of the same type
+func (t Text) ComposeChange(change Text, keepNil bool) Text {
+	return Text{Embed: composeEmbed(t.Embed, change.Embed, keepNil)}
+}
+
+// TransformChange transforms the change of an embed against t, a concurrent change to the same embed,
+// with the EmbedHandler registered for it. It returns false if there is none
+func (t Text) TransformChange(change Text, priority bool) (Text, bool) {
+	embed, ok := transformEmbed(t.Embed, change.Embed, priority)
+	return Text{Embed: embed}, ok
+}
+
+// InvertChange returns the change that undoes t, a change to the embed of base
+func (t Text) InvertChange(base Text) Text {
+	return Text{Embed: invertEmbed(t.Embed, base.Embed)}
+}
+
+// Seq returns a copy of the Delta as a Seq[Text]. Retain embeds become retains of 1 with a Change
+func (d *Delta) Seq() *Seq[Text] {
+	return d.Clone().seq()
+}
+
+// seq returns the Delta as a Seq[Text] that shares its text, embeds and attributes.
+// Ops with no length are left out
+func (d *Delta) seq() *Seq[Text] {
+	ops := make([]SeqOp[Text], 0, len(d.Ops))
+	for _, op := range d.Ops {
+		switch {
+		case op.RetainEmbed != nil:
+			ops = append(ops, SeqOp[Text]{Retain: 1, Change: &Text{Embed: op.RetainEmbed}, Attributes: op.Attributes})
+		case op.InsertEmbed != nil:
+			ops = append(ops, SeqOp[Text]{Insert: Text{Embed: op.InsertEmbed}, Attributes: op.Attributes})
+		case OpsLength(op) <= 0:
+			continue
+		case op.Delete != nil:
+			ops = append(ops, SeqOp[Text]{Delete: *op.Delete})
+		case op.Retain != nil:
+			ops = append(ops, SeqOp[Text]{Retain: *op.Retain, Attributes: op.Attributes})
+		default:
+			ops = append(ops, SeqOp[Text]{Insert: Text{Runes: op.Insert}, Attributes: op.Attributes})
+		}
+	}
+	return NewSeq(ops)
+}
+
+// FromSeq returns the Seq[Text] s as a Delta that shares nothing with s
+func FromSeq(s Seq[Text]) *Delta {
+	delta := New(nil)
+	for _, op := range s.Ops {
+		switch {
+		case op.Delete > 0:
+			delta.Delete(op.Delete)
+		case op.Change != nil:
+			delta.Push(Op{RetainEmbed: op.Change.Embed, Attributes: op.Attributes})
+		case op.Retain > 0:
+			delta.Push(Op{Retain: &op.Retain, Attributes: op.Attributes})
+		case op.Insert.Embed != nil:
+			delta.Push(Op{InsertEmbed: op.Insert.Embed, Attributes: op.Attributes})
+		default:
+			delta.Push(Op{Insert: op.Insert.Runes, Attributes: op.Attributes})
+		}
+	}
+	return delta
+}
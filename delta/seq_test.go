@@ -0,0 +1,183 @@
+package delta
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// blocks is a Content made of a list of block ids
+type blocks []string
+
+func (b blocks) Len() int {
+	return len(b)
+}
+
+func (b blocks) Slice(i, j int) blocks {
+	return b[i:j:j]
+}
+
+func (b blocks) Merge(other blocks) (blocks, bool) {
+	return append(b[:len(b):len(b)], other...), true
+}
+
+func TestSeqPush(t *testing.T) {
+	seq := NewSeq[blocks](nil).
+		Insert(blocks{"a"}, nil).
+		Insert(blocks{"b", "c"}, nil).
+		Insert(nil, nil).
+		Delete(1).
+		Delete(2).
+		Insert(blocks{"d"}, AttributeMap{"done": true}).
+		Retain(1, nil).
+		Retain(2, nil)
+	expected := NewSeq([]SeqOp[blocks]{
+		{Insert: blocks{"a", "b", "c"}},
+		{Insert: blocks{"d"}, Attributes: AttributeMap{"done": true}},
+		{Delete: 3},
+		{Retain: 3},
+	})
+	if !reflect.DeepEqual(expected, seq) {
+		t.Errorf("expected %+v but got %+v\n", expected, seq)
+	}
+	if x := seq.Length(); x != 10 {
+		t.Error("expected 10 but got ", x)
+	}
+	if x := seq.ChangeLength(); x != 1 {
+		t.Error("expected 1 but got ", x)
+	}
+}
+
+func TestSeqCompose(t *testing.T) {
+	doc := NewSeq[blocks](nil).Insert(blocks{"a", "b", "c", "d"}, nil)
+	change := NewSeq[blocks](nil).Retain(1, AttributeMap{"done": true}).Delete(2).Insert(blocks{"x"}, nil)
+	expected := NewSeq[blocks](nil).
+		Insert(blocks{"a"}, AttributeMap{"done": true}).
+		Insert(blocks{"x", "d"}, nil)
+	if x := doc.Compose(*change); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestSeqTransform(t *testing.T) {
+	a := NewSeq[blocks](nil).Insert(blocks{"a"}, nil)
+	b := NewSeq[blocks](nil).Insert(blocks{"b"}, nil)
+	expected := NewSeq[blocks](nil).Retain(1, nil).Insert(blocks{"b"}, nil)
+	if x := a.Transform(*b, true); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	expected = NewSeq[blocks](nil).Insert(blocks{"b"}, nil)
+	if x := a.Transform(*b, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+
+	// both orders converge
+	doc := NewSeq[blocks](nil).Insert(blocks{"1", "2", "3"}, nil)
+	a = NewSeq[blocks](nil).Retain(1, nil).Delete(1).Insert(blocks{"a"}, nil)
+	b = NewSeq[blocks](nil).Retain(2, nil).Insert(blocks{"b"}, nil).Retain(1, AttributeMap{"done": true})
+	left := doc.Compose(*a).Compose(*a.Transform(*b, true))
+	right := doc.Compose(*b).Compose(*b.Transform(*a, false))
+	if !reflect.DeepEqual(left, right) {
+		t.Errorf("expected %+v to equal %+v\n", left, right)
+	}
+}
+
+func TestSeqTransformPosition(t *testing.T) {
+	seq := NewSeq[blocks](nil).Retain(1, nil).Insert(blocks{"a", "b"}, nil).Delete(1)
+	for _, c := range [][3]int{{0, 0, 0}, {1, 1, 3}, {2, 3, 3}, {4, 5, 5}} {
+		if x := seq.TransformPosition(c[0], true); x != c[1] {
+			t.Errorf("%d: expected %d but got %d\n", c[0], c[1], x)
+		}
+		if x := seq.TransformPosition(c[0], false); x != c[2] {
+			t.Errorf("%d: expected %d but got %d\n", c[0], c[2], x)
+		}
+	}
+}
+
+func TestSeqInvert(t *testing.T) {
+	base := NewSeq[blocks](nil).Insert(blocks{"a", "b"}, AttributeMap{"done": true}).Insert(blocks{"c"}, nil)
+	change := NewSeq[blocks](nil).Delete(1).Retain(2, AttributeMap{"done": nil}).Insert(blocks{"d"}, nil)
+	inverted := change.Invert(*base)
+	expected := NewSeq[blocks](nil).
+		Insert(blocks{"a"}, AttributeMap{"done": true}).
+		Retain(1, AttributeMap{"done": true}).
+		Retain(1, AttributeMap{"done": nil}).
+		Delete(1)
+	if !reflect.DeepEqual(expected, inverted) {
+		t.Errorf("expected %+v but got %+v\n", expected, inverted)
+	}
+	if x := base.Compose(*change).Compose(*inverted); !reflect.DeepEqual(base, x) {
+		t.Errorf("expected %+v but got %+v\n", base, x)
+	}
+}
+
+func TestSeqSlice(t *testing.T) {
+	seq := NewSeq[blocks](nil).Insert(blocks{"a", "b"}, nil).Insert(blocks{"c", "d"}, AttributeMap{"done": true})
+	expected := NewSeq[blocks](nil).Insert(blocks{"b"}, nil).Insert(blocks{"c"}, AttributeMap{"done": true})
+	if x := seq.Slice(1, 3); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestSeqText(t *testing.T) {
+	bold := map[string]interface{}{"bold": true}
+	doc := New(nil).Insert("Hello😀", bold).InsertEmbed(Embed{"image": "a.png"}, nil).Insert("World\n", nil)
+	seq := doc.Seq()
+	if x := FromSeq(*seq); !reflect.DeepEqual(doc, x) {
+		t.Errorf("expected %+v but got %+v\n", doc, x)
+	}
+	seq.Ops[0].Insert.Runes[0] = 'J'
+	if x := string(doc.Ops[0].Insert); x != "Hello😀" {
+		t.Error("changing the Seq changed the Delta ", x)
+	}
+
+	change := NewSeq[Text](nil).Retain(5, AttributeMap{"bold": nil}).Delete(2).Insert(Text{Runes: []rune("!")}, nil)
+	expected := New(nil).Insert("Hello!", nil).InsertEmbed(Embed{"image": "a.png"}, nil).Insert("World\n", nil)
+	if x := FromSeq(*doc.Seq().Compose(*change)); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestSeqChange(t *testing.T) {
+	RegisterEmbed("delta", deltaEmbed{})
+	defer UnregisterEmbed("delta")
+
+	embed := func(text string) *Text {
+		return &Text{Embed: Embed{"delta": New(nil).Insert(text, nil).Ops}}
+	}
+	doc := NewSeq[Text](nil).Insert(Text{Runes: []rune("x")}, nil).Insert(*embed("a"), nil)
+	change := NewSeq[Text](nil).Retain(1, nil).Push(SeqOp[Text]{Retain: 1, Change: embed("b")}).Retain(1, nil).Retain(2, nil)
+	if x := len(change.Chop().Ops); x != 2 {
+		t.Errorf("expected the change not to be merged or chopped but got %+v\n", change.Ops)
+	}
+
+	x := FromSeq(*doc.Compose(*change))
+	expected := New(nil).Insert("x", nil).InsertEmbed(Embed{"delta": New(nil).Insert("ba", nil).Ops}, nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+
+	other := NewSeq[Text](nil).Retain(1, nil).Push(SeqOp[Text]{Retain: 1, Change: embed("c")})
+	x = FromSeq(*change.Transform(*other, true))
+	expected = New(nil).Retain(1, nil).RetainEmbed(Embed{"delta": New(nil).Retain(1, nil).Insert("c", nil).Ops}, nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+
+	x = FromSeq(*change.Invert(*doc))
+	expected = New(nil).Retain(1, nil).RetainEmbed(Embed{"delta": New(nil).Delete(1).Ops}, nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestSeqChangeWithoutChanger(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrNoChanger) {
+			t.Errorf("expected ErrNoChanger but got %+v\n", err)
+		}
+	}()
+	doc := NewSeq[blocks](nil).Insert(blocks{"a"}, nil)
+	doc.Compose(*NewSeq[blocks](nil).Push(SeqOp[blocks]{Retain: 1, Change: &blocks{"b"}}))
+}
@@ -0,0 +1,289 @@
+package delta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TableEmbedType is the embed type Quill uses for tables, {"table-embed": {...}}
+const TableEmbedType = "table-embed"
+
+// ErrInvalidTable is used when the value of a table embed is not a table, TableHandler panics with it
+var ErrInvalidTable = errors.New("invalid table embed")
+
+// Table is the value of a table embed, following Quill's table-embed data model.
+// Rows and Columns are deltas of {"id": "..."} embeds, one per row or column,
+// and Cells holds the cells that have content or attributes, keyed by their 1-based
+// "row:column" identity, see CellID. In a change, cell identities are positions
+// once the Rows and Columns changes have been applied
+type Table struct {
+	Rows    Delta
+	Columns Delta
+	Cells   map[string]TableCell
+}
+
+// TableCell holds the content of a cell, as a delta, and its attributes
+type TableCell struct {
+	Content    Delta
+	Attributes AttributeMap
+}
+
+// CellID returns the identity of the cell at row and column, counting from 0, like "1:1" for the first cell
+func CellID(row, column int) string {
+	return strconv.Itoa(row+1) + ":" + strconv.Itoa(column+1)
+}
+
+// parseCellID returns the row and column of a cell identity, counting from 0
+func parseCellID(id string) (int, int, error) {
+	r, c, found := strings.Cut(id, ":")
+	row, rowErr := strconv.Atoi(r)
+	column, columnErr := strconv.Atoi(c)
+	if !found || rowErr != nil || columnErr != nil || row < 1 || column < 1 {
+		return 0, 0, fmt.Errorf("%w: cell %q", ErrInvalidTable, id)
+	}
+	return row - 1, column - 1, nil
+}
+
+// ParseTable reads the value of a table embed, as found in a decoded Delta or returned by Table.Value.
+// A nil value is an empty table
+func ParseTable(value interface{}) (Table, error) {
+	in, err := json.Marshal(value)
+	if err != nil {
+		return Table{}, fmt.Errorf("%w: %v", ErrInvalidTable, err)
+	}
+	var data struct {
+		Rows    []Op `json:"rows"`
+		Columns []Op `json:"columns"`
+		Cells   map[string]struct {
+			Content    []Op         `json:"content"`
+			Attributes AttributeMap `json:"attributes"`
+		} `json:"cells"`
+	}
+	if err := json.Unmarshal(in, &data); err != nil {
+		return Table{}, fmt.Errorf("%w: %v", ErrInvalidTable, err)
+	}
+	table := Table{
+		Rows:    *New(data.Rows),
+		Columns: *New(data.Columns),
+		Cells:   make(map[string]TableCell, len(data.Cells)),
+	}
+	for id, cell := range data.Cells {
+		if _, _, err := parseCellID(id); err != nil {
+			return Table{}, err
+		}
+		table.Cells[id] = TableCell{Content: *New(cell.Content), Attributes: cell.Attributes}
+	}
+	return table, nil
+}
+
+// Value returns the table as the value of a table embed, with the same shape as a decoded JSON one.
+// Empty rows, columns and cells are left out, like Quill does
+func (t Table) Value() interface{} {
+	value := map[string]interface{}{}
+	if t.Rows.Length() > 0 {
+		value["rows"] = t.Rows.Ops
+	}
+	if t.Columns.Length() > 0 {
+		value["columns"] = t.Columns.Ops
+	}
+	cells := map[string]interface{}{}
+	for id, cell := range t.Cells {
+		data := map[string]interface{}{}
+		if cell.Content.Length() > 0 {
+			data["content"] = cell.Content.Ops
+		}
+		if len(cell.Attributes) > 0 {
+			data["attributes"] = cell.Attributes
+		}
+		if len(data) > 0 {
+			cells[id] = data
+		}
+	}
+	if len(cells) > 0 {
+		value["cells"] = cells
+	}
+	// go through JSON so ops become plain objects, and values compare and clone like any other embed
+	in, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Errorf("%w: %v", ErrInvalidTable, err))
+	}
+	var out interface{}
+	decoder := json.NewDecoder(bytes.NewReader(in))
+	decoder.UseNumber()
+	if err := decoder.Decode(&out); err != nil {
+		panic(fmt.Errorf("%w: %v", ErrInvalidTable, err))
+	}
+	return out
+}
+
+// Embed returns the table as a table embed, to use with InsertEmbed or RetainEmbed
+func (t Table) Embed() Embed {
+	return Embed{TableEmbedType: t.Value()}
+}
+
+// Compose returns the change t followed by the change other, or the table t once other is applied on it.
+// Cells of t move with the rows and columns inserted or deleted by other, and are dropped with them
+func (t Table) Compose(other Table, keepNil bool) Table {
+	table := Table{
+		Rows:    *t.Rows.Compose(other.Rows),
+		Columns: *t.Columns.Compose(other.Columns),
+		Cells:   reindexCells(t.Cells, other.Rows, other.Columns),
+	}
+	for id, b := range other.Cells {
+		a := table.Cells[id]
+		setCell(table.Cells, id, TableCell{
+			Content:    *a.Content.Compose(b.Content),
+			Attributes: a.Attributes.Compose(b.Attributes, keepNil),
+		})
+	}
+	return table
+}
+
+// Transform returns the change other once t has been applied, like Delta.Transform.
+// Concurrent edits to different cells are both kept, edits to the same cell are transformed
+// against each other, and rows or columns inserted at the same place are ordered by priority
+func (t Table) Transform(other Table, priority bool) Table {
+	table := Table{
+		Rows:    *t.Rows.Transform(other.Rows, priority),
+		Columns: *t.Columns.Transform(other.Columns, priority),
+	}
+	// cells of other are where other's rows and columns put them, move them past t's ones
+	table.Cells = reindexCells(other.Cells, *other.Rows.Transform(t.Rows, !priority), *other.Columns.Transform(t.Columns, !priority))
+	for id, a := range t.Cells {
+		row, column, err := parseCellID(id)
+		if err != nil {
+			panic(err)
+		}
+		row, rowOk := composePosition(table.Rows, row)
+		column, columnOk := composePosition(table.Columns, column)
+		if !rowOk || !columnOk {
+			continue
+		}
+		newID := CellID(row, column)
+		b, ok := table.Cells[newID]
+		if !ok {
+			continue
+		}
+		setCell(table.Cells, newID, TableCell{
+			Content:    *a.Content.Transform(b.Content, priority),
+			Attributes: a.Attributes.Transform(b.Attributes, priority),
+		})
+	}
+	return table
+}
+
+// Invert returns the change that undoes t when applied on top of the table base, like Delta.Invert.
+// Cells of base dropped with a deleted row or column come back with it
+func (t Table) Invert(base Table) Table {
+	table := Table{
+		Rows:    *t.Rows.Invert(base.Rows),
+		Columns: *t.Columns.Invert(base.Columns),
+	}
+	table.Cells = reindexCells(t.Cells, table.Rows, table.Columns)
+	for id, change := range table.Cells {
+		baseCell := base.Cells[id]
+		setCell(table.Cells, id, TableCell{
+			Content:    *change.Content.Invert(baseCell.Content),
+			Attributes: change.Attributes.Invert(baseCell.Attributes),
+		})
+	}
+	for id, cell := range base.Cells {
+		row, column, err := parseCellID(id)
+		if err != nil {
+			panic(err)
+		}
+		_, rowOk := composePosition(t.Rows, row)
+		_, columnOk := composePosition(t.Columns, column)
+		if !rowOk || !columnOk {
+			table.Cells[id] = TableCell{Content: *cell.Content.Clone(), Attributes: cell.Attributes.clone().orNil()}
+		}
+	}
+	return table
+}
+
+// setCell puts cell at id in cells, or removes id if the cell has no content and no attributes
+func setCell(cells map[string]TableCell, id string, cell TableCell) {
+	if cell.Content.Length() == 0 && len(cell.Attributes) == 0 {
+		delete(cells, id)
+		return
+	}
+	cells[id] = cell
+}
+
+// reindexCells returns cells moved by the rows and columns changes, cells in deleted rows or columns are dropped
+func reindexCells(cells map[string]TableCell, rows, columns Delta) map[string]TableCell {
+	reindexed := make(map[string]TableCell, len(cells))
+	for id, cell := range cells {
+		row, column, err := parseCellID(id)
+		if err != nil {
+			panic(err)
+		}
+		row, rowOk := composePosition(rows, row)
+		column, columnOk := composePosition(columns, column)
+		if rowOk && columnOk {
+			reindexed[CellID(row, column)] = cell
+		}
+	}
+	return reindexed
+}
+
+// composePosition returns where index is once d is applied, or false if d deletes it.
+// Unlike TransformPosition, an insert right at index moves it
+func composePosition(d Delta, index int) (int, bool) {
+	iter := NewIterator(d.Ops)
+	offset := 0
+	for iter.HasNext() && offset <= index {
+		length := iter.PeekLength()
+		switch iter.PeekType() {
+		case OpDelete:
+			if length > index-offset {
+				return 0, false
+			}
+			index -= length
+		case OpInsert:
+			index += length
+			offset += length
+		default:
+			offset += length
+		}
+		iter.NextOp()
+	}
+	return index, true
+}
+
+// TableHandler is the EmbedHandler for table embeds, see RegisterTableEmbed
+type TableHandler struct{}
+
+// parseTableValue is ParseTable for the handler, which panics like composeEmbed does
+func parseTableValue(value interface{}) Table {
+	table, err := ParseTable(value)
+	if err != nil {
+		panic(err)
+	}
+	return table
+}
+
+// Compose composes two table embed values, see Table.Compose
+func (TableHandler) Compose(a, b interface{}, keepNil bool) interface{} {
+	return parseTableValue(a).Compose(parseTableValue(b), keepNil).Value()
+}
+
+// Transform transforms the table embed value b against a, see Table.Transform
+func (TableHandler) Transform(a, b interface{}, priority bool) interface{} {
+	return parseTableValue(a).Transform(parseTableValue(b), priority).Value()
+}
+
+// Invert inverts the table embed value a on top of the table b, see Table.Invert
+func (TableHandler) Invert(a, b interface{}) interface{} {
+	return parseTableValue(a).Invert(parseTableValue(b)).Value()
+}
+
+// RegisterTableEmbed registers a TableHandler for TableEmbedType, so deltas with tables can be
+// composed, transformed and inverted. Use RegisterEmbed to handle tables stored under another embed type
+func RegisterTableEmbed() {
+	RegisterEmbed(TableEmbedType, TableHandler{})
+}
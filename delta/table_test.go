@@ -0,0 +1,293 @@
+package delta
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// tableFromJSON reads a table embed value written as JSON
+func tableFromJSON(s string) Table {
+	var value interface{}
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		panic(err)
+	}
+	table, err := ParseTable(value)
+	if err != nil {
+		panic(err)
+	}
+	return table
+}
+
+func TestCellID(t *testing.T) {
+	if x := CellID(0, 2); x != "1:3" {
+		t.Errorf("expected '1:3' but got '%s'\n", x)
+	}
+	row, column, err := parseCellID("2:4")
+	if row != 1 || column != 3 || err != nil {
+		t.Errorf("expected 1 3 <nil> but got %d %d %v\n", row, column, err)
+	}
+	for _, id := range []string{"", "1", "0:1", "a:1", "1:-1"} {
+		if _, _, err := parseCellID(id); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("expected ErrInvalidTable for %q but got %v\n", id, err)
+		}
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	x := tableFromJSON(`{"rows": [{"insert": {"id": "r1"}}], "cells": {"1:1": {"content": [{"insert": "a"}], "attributes": {"align": "center"}}}}`)
+	expected := Table{
+		Rows: *New(nil).InsertEmbed(Embed{"id": "r1"}, nil),
+		Cells: map[string]TableCell{
+			"1:1": {Content: *New(nil).Insert("a", nil), Attributes: AttributeMap{"align": "center"}},
+		},
+	}
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+
+	if x, err := ParseTable(nil); err != nil || len(x.Cells) != 0 || x.Rows.Length() != 0 {
+		t.Errorf("expected an empty table but got %+v %v\n", x, err)
+	}
+	if _, err := ParseTable(map[string]interface{}{"rows": "r1"}); !errors.Is(err, ErrInvalidTable) {
+		t.Errorf("expected ErrInvalidTable but got %v\n", err)
+	}
+	if _, err := ParseTable(map[string]interface{}{"cells": map[string]interface{}{"x": nil}}); !errors.Is(err, ErrInvalidTable) {
+		t.Errorf("expected ErrInvalidTable but got %v\n", err)
+	}
+}
+
+func TestTableValueLeavesOutEmptyParts(t *testing.T) {
+	x := Table{Cells: map[string]TableCell{"1:1": {}}}.Value()
+	expected := map[string]interface{}{}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestComposePosition(t *testing.T) {
+	d := New(nil).Retain(1, nil).Insert("a", nil).Delete(1)
+	for _, tc := range []struct {
+		index, expected int
+		ok              bool
+	}{
+		{0, 0, true},
+		{1, 0, false},
+		{2, 2, true},
+		{3, 3, true},
+	} {
+		if x, ok := composePosition(*d, tc.index); x != tc.expected || ok != tc.ok {
+			t.Errorf("index %d: expected %d %v but got %d %v\n", tc.index, tc.expected, tc.ok, x, ok)
+		}
+	}
+}
+
+func TestTableComposeInsertRow(t *testing.T) {
+	base := tableFromJSON(`{
+		"rows": [{"insert": {"id": "r1"}}, {"insert": {"id": "r2"}}],
+		"columns": [{"insert": {"id": "c1"}}, {"insert": {"id": "c2"}}],
+		"cells": {"1:2": {"content": [{"insert": "Hello"}]}}
+	}`)
+	change := tableFromJSON(`{
+		"rows": [{"insert": {"id": "r3"}}],
+		"cells": {"1:1": {"content": [{"insert": "World"}]}}
+	}`)
+	x := base.Compose(change, false)
+	expected := tableFromJSON(`{
+		"rows": [{"insert": {"id": "r3"}}, {"insert": {"id": "r1"}}, {"insert": {"id": "r2"}}],
+		"columns": [{"insert": {"id": "c1"}}, {"insert": {"id": "c2"}}],
+		"cells": {"1:1": {"content": [{"insert": "World"}]}, "2:2": {"content": [{"insert": "Hello"}]}}
+	}`)
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+}
+
+func TestTableComposeCells(t *testing.T) {
+	base := tableFromJSON(`{
+		"rows": [{"insert": {"id": "r1"}}, {"insert": {"id": "r2"}}],
+		"columns": [{"insert": {"id": "c1"}}, {"insert": {"id": "c2"}}],
+		"cells": {"1:2": {"content": [{"insert": "Hello"}], "attributes": {"align": "right"}}}
+	}`)
+	change := tableFromJSON(`{
+		"cells": {
+			"1:2": {"content": [{"retain": 5}, {"insert": " World"}], "attributes": {"align": null}},
+			"2:1": {"attributes": {"align": "center"}}
+		}
+	}`)
+	x := base.Compose(change, false)
+	expected := tableFromJSON(`{
+		"rows": [{"insert": {"id": "r1"}}, {"insert": {"id": "r2"}}],
+		"columns": [{"insert": {"id": "c1"}}, {"insert": {"id": "c2"}}],
+		"cells": {"1:2": {"content": [{"insert": "Hello World"}]}, "2:1": {"attributes": {"align": "center"}}}
+	}`)
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+}
+
+func TestTableComposeDeleteRowDropsCells(t *testing.T) {
+	base := tableFromJSON(`{
+		"rows": [{"insert": {"id": "r1"}}, {"insert": {"id": "r2"}}],
+		"columns": [{"insert": {"id": "c1"}}],
+		"cells": {"1:1": {"content": [{"insert": "a"}]}, "2:1": {"content": [{"insert": "b"}]}}
+	}`)
+	change := tableFromJSON(`{"rows": [{"delete": 1}]}`)
+	x := base.Compose(change, false)
+	expected := tableFromJSON(`{
+		"rows": [{"insert": {"id": "r2"}}],
+		"columns": [{"insert": {"id": "c1"}}],
+		"cells": {"1:1": {"content": [{"insert": "b"}]}}
+	}`)
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+}
+
+func TestTableTransformDifferentCells(t *testing.T) {
+	a := tableFromJSON(`{"cells": {"1:1": {"content": [{"insert": "a"}]}}}`)
+	b := tableFromJSON(`{"cells": {"2:2": {"content": [{"insert": "b"}]}}}`)
+	x := a.Transform(b, true)
+	if !reflect.DeepEqual(b.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", b.Value(), x.Value())
+	}
+}
+
+func TestTableTransformSameCell(t *testing.T) {
+	a := tableFromJSON(`{"cells": {"1:1": {"content": [{"insert": "a"}], "attributes": {"align": "left"}}}}`)
+	b := tableFromJSON(`{"cells": {"1:1": {"content": [{"insert": "b"}], "attributes": {"align": "right"}}}}`)
+
+	x := a.Transform(b, true)
+	expected := tableFromJSON(`{"cells": {"1:1": {"content": [{"retain": 1}, {"insert": "b"}]}}}`)
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+
+	x = a.Transform(b, false)
+	expected = tableFromJSON(`{"cells": {"1:1": {"content": [{"insert": "b"}], "attributes": {"align": "right"}}}}`)
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+}
+
+func TestTableTransformInsertColumnMovesCells(t *testing.T) {
+	a := tableFromJSON(`{"columns": [{"insert": {"id": "c2"}}]}`)
+	b := tableFromJSON(`{"cells": {"1:1": {"content": [{"insert": "b"}]}}}`)
+	x := a.Transform(b, true)
+	expected := tableFromJSON(`{"cells": {"1:2": {"content": [{"insert": "b"}]}}}`)
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+}
+
+func TestTableTransformDeleteRowDropsCells(t *testing.T) {
+	a := tableFromJSON(`{"rows": [{"delete": 1}]}`)
+	b := tableFromJSON(`{"cells": {"1:1": {"content": [{"insert": "b"}]}, "2:1": {"content": [{"insert": "c"}]}}}`)
+	x := a.Transform(b, true)
+	expected := tableFromJSON(`{"cells": {"1:1": {"content": [{"insert": "c"}]}}}`)
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+}
+
+func TestTableTransformInsertRows(t *testing.T) {
+	a := tableFromJSON(`{"rows": [{"insert": {"id": "ra"}}], "cells": {"1:1": {"content": [{"insert": "a"}]}}}`)
+	b := tableFromJSON(`{"rows": [{"insert": {"id": "rb"}}], "cells": {"1:1": {"content": [{"insert": "b"}]}}}`)
+
+	x := a.Transform(b, true)
+	expected := tableFromJSON(`{"rows": [{"retain": 1}, {"insert": {"id": "rb"}}], "cells": {"2:1": {"content": [{"insert": "b"}]}}}`)
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+
+	x = b.Transform(a, false)
+	expected = tableFromJSON(`{"rows": [{"insert": {"id": "ra"}}], "cells": {"1:1": {"content": [{"insert": "a"}]}}}`)
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+}
+
+func TestTableInvert(t *testing.T) {
+	base := tableFromJSON(`{
+		"rows": [{"insert": {"id": "r1"}}, {"insert": {"id": "r2"}}],
+		"columns": [{"insert": {"id": "c1"}}],
+		"cells": {"1:1": {"content": [{"insert": "a"}], "attributes": {"align": "left"}}, "2:1": {"content": [{"insert": "b"}]}}
+	}`)
+	change := tableFromJSON(`{
+		"rows": [{"retain": 1}, {"delete": 1}],
+		"cells": {"1:1": {"content": [{"delete": 1}, {"insert": "x"}], "attributes": {"align": "right"}}}
+	}`)
+	x := change.Invert(base)
+	expected := tableFromJSON(`{
+		"rows": [{"retain": 1}, {"insert": {"id": "r2"}}],
+		"cells": {"1:1": {"content": [{"insert": "a"}, {"delete": 1}], "attributes": {"align": "left"}}, "2:1": {"content": [{"insert": "b"}]}}
+	}`)
+	if !reflect.DeepEqual(expected.Value(), x.Value()) {
+		t.Errorf("expected %+v but got %+v\n", expected.Value(), x.Value())
+	}
+	if y := base.Compose(change, false).Compose(x, false); !reflect.DeepEqual(base.Value(), y.Value()) {
+		t.Errorf("expected %+v but got %+v\n", base.Value(), y.Value())
+	}
+}
+
+func TestTableEmbedCompose(t *testing.T) {
+	RegisterTableEmbed()
+	defer UnregisterEmbed(TableEmbedType)
+
+	doc := New(nil).Insert("a\n", nil).InsertEmbed(tableFromJSON(`{
+		"rows": [{"insert": {"id": "r1"}}],
+		"columns": [{"insert": {"id": "c1"}}],
+		"cells": {"1:1": {"content": [{"insert": "x"}]}}
+	}`).Embed(), nil).Insert("\n", nil)
+	change := New(nil).Retain(2, nil).RetainEmbed(tableFromJSON(`{"columns": [{"retain": 1}, {"insert": {"id": "c2"}}], "cells": {"1:2": {"content": [{"insert": "y"}]}}}`).Embed(), nil)
+	x := doc.Compose(*change)
+	expected := New(nil).Insert("a\n", nil).InsertEmbed(tableFromJSON(`{
+		"rows": [{"insert": {"id": "r1"}}],
+		"columns": [{"insert": {"id": "c1"}}, {"insert": {"id": "c2"}}],
+		"cells": {"1:1": {"content": [{"insert": "x"}]}, "1:2": {"content": [{"insert": "y"}]}}
+	}`).Embed(), nil).Insert("\n", nil)
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+
+	// a delta read from JSON composes the same way
+	decoded, err := FromJSON([]byte(`{"ops": [{"retain": 2}, {"retain": {"table-embed": {"columns": [{"retain": 1}, {"insert": {"id": "c2"}}], "cells": {"1:2": {"content": [{"insert": "y"}]}}}}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := doc.Compose(*decoded); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestTableEmbedConcurrentEdits(t *testing.T) {
+	RegisterTableEmbed()
+	defer UnregisterEmbed(TableEmbedType)
+
+	doc := New(nil).InsertEmbed(tableFromJSON(`{
+		"rows": [{"insert": {"id": "r1"}}, {"insert": {"id": "r2"}}],
+		"columns": [{"insert": {"id": "c1"}}, {"insert": {"id": "c2"}}],
+		"cells": {"1:1": {"content": [{"insert": "x"}]}, "2:2": {"content": [{"insert": "y"}]}}
+	}`).Embed(), nil)
+	changes := []*Delta{
+		New(nil).RetainEmbed(tableFromJSON(`{"cells": {"1:1": {"content": [{"insert": "a"}]}}}`).Embed(), nil),
+		New(nil).RetainEmbed(tableFromJSON(`{"cells": {"2:2": {"content": [{"retain": 1}, {"insert": "b"}]}}}`).Embed(), nil),
+		New(nil).RetainEmbed(tableFromJSON(`{"rows": [{"retain": 1}, {"insert": {"id": "ra"}}], "cells": {"2:1": {"content": [{"insert": "c"}]}}}`).Embed(), nil),
+		New(nil).RetainEmbed(tableFromJSON(`{"rows": [{"retain": 1}, {"insert": {"id": "rb"}}], "cells": {"2:2": {"content": [{"insert": "d"}]}}}`).Embed(), nil),
+		New(nil).RetainEmbed(tableFromJSON(`{"columns": [{"insert": {"id": "ca"}}], "cells": {"1:1": {"attributes": {"align": "center"}}}}`).Embed(), nil),
+		New(nil).RetainEmbed(tableFromJSON(`{"rows": [{"delete": 1}]}`).Embed(), nil),
+	}
+	for i, a := range changes {
+		for j, b := range changes {
+			if i == j {
+				continue
+			}
+			x := doc.Compose(*a).Compose(*a.Transform(*b, true))
+			y := doc.Compose(*b).Compose(*b.Transform(*a, false))
+			if !reflect.DeepEqual(x, y) {
+				t.Errorf("%d then %d: expected %+v but got %+v\n", i, j, x, y)
+			}
+		}
+	}
+}
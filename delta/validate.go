@@ -0,0 +1,136 @@
+package delta
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMultipleOpKinds is used when an Op has more than one of insert, retain and delete
+var ErrMultipleOpKinds = errors.New("op has more than one kind")
+
+// ErrNonPositiveLength is used when an Op has a length of 0 or less, like a negative retain or an empty insert
+var ErrNonPositiveLength = errors.New("op length must be positive")
+
+// ErrNotADocument is used when a document has an Op that is not an insert
+var ErrNotADocument = errors.New("document can only have inserts")
+
+// ErrPastEndOfDocument is used by ApplyTo when a change retains or deletes past the end of the document
+var ErrPastEndOfDocument = errors.New("op goes past the end of the document")
+
+// OpError tells you which Op of a Delta is invalid, and why in Err
+type OpError struct {
+	Index int
+	Err   error
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("op %d: %s", e.Index, e.Err)
+}
+
+// Unwrap returns Err, so errors.Is(err, ErrNotADocument) and friends work on an *OpError
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateOptions changes what Validate checks
+type ValidateOptions struct {
+	// Document requires every Op to be an insert
+	Document bool
+}
+
+// Validate checks that every Op of the Delta is well formed, it returns an *OpError for the first one that isn't
+func (d *Delta) Validate(opts ValidateOptions) error {
+	for i, op := range d.Ops {
+		kinds := 0
+		for _, set := range []bool{op.Insert != nil, op.InsertEmbed != nil, op.Retain != nil, op.RetainEmbed != nil, op.Delete != nil} {
+			if set {
+				kinds++
+			}
+		}
+		switch {
+		case kinds > 1:
+			return &OpError{Index: i, Err: ErrMultipleOpKinds}
+		case kinds == 0,
+			op.Insert != nil && len(op.Insert) == 0,
+			op.Retain != nil && *op.Retain <= 0,
+			op.Delete != nil && *op.Delete <= 0:
+			return &OpError{Index: i, Err: ErrNonPositiveLength}
+		case opts.Document && !op.isInsert():
+			return &OpError{Index: i, Err: ErrNotADocument}
+		}
+	}
+	return nil
+}
+
+// IsDocument tells you if the Delta only has inserts, so it can be used as a document
+func (d *Delta) IsDocument() bool {
+	for _, op := range d.Ops {
+		if !op.isInsert() {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyTo validates the Delta as a change and doc as a document, then returns doc with the change applied.
+// It fails with ErrPastEndOfDocument instead of making up text when the change is longer than doc,
+// and with an error instead of the panics of Compose when the change splits a surrogate pair of doc
+// or changes an embed it can't, see CheckSurrogates and EmbedHandler
+func (d *Delta) ApplyTo(doc Delta) (applied *Delta, err error) {
+	if err := doc.Validate(ValidateOptions{Document: true}); err != nil {
+		return nil, fmt.Errorf("document: %w", err)
+	}
+	if err := d.Validate(ValidateOptions{}); err != nil {
+		return nil, err
+	}
+	length := doc.Length()
+	offset := 0
+	for i, op := range d.Ops {
+		if op.isInsert() {
+			continue
+		}
+		offset += OpsLength(op)
+		if offset > length {
+			return nil, &OpError{Index: i, Err: ErrPastEndOfDocument}
+		}
+	}
+	if err := d.CheckSurrogates(doc); err != nil {
+		return nil, err
+	}
+	if err := d.checkEmbeds(doc); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// an EmbedHandler can still fail on values it doesn't understand
+		if r := recover(); r != nil {
+			recovered, ok := r.(error)
+			if !ok {
+				panic(r)
+			}
+			applied, err = nil, recovered
+		}
+	}()
+	return doc.Compose(*d), nil
+}
+
+// checkEmbeds returns an *OpError for the first retain embed of the Delta that Compose can't apply on doc,
+// because doc has text or another type of embed there, or because there is no EmbedHandler for it.
+// The Delta must not go past the end of doc or split its surrogate pairs
+func (d *Delta) checkEmbeds(doc Delta) error {
+	iter := NewIterator(doc.Ops)
+	for i, op := range d.Ops {
+		if op.isInsert() {
+			continue
+		}
+		if op.RetainEmbed != nil {
+			if _, _, _, _, err := embedHandlerFor(iter.Next(1).InsertEmbed, op.RetainEmbed); err != nil {
+				return &OpError{Index: i, Err: err}
+			}
+			continue
+		}
+		for length := OpsLength(op); length > 0 && iter.HasNext(); {
+			length -= OpsLength(iter.Next(length))
+		}
+	}
+	return nil
+}
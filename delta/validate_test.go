@@ -0,0 +1,138 @@
+package delta
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func expectOpError(t *testing.T, err error, target error, index int) {
+	t.Helper()
+	var opErr *OpError
+	if !errors.Is(err, target) || !errors.As(err, &opErr) || opErr.Index != index {
+		t.Errorf("expected %v at op %d but got %v\n", target, index, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	delta := New(nil).Insert("abc", map[string]interface{}{"bold": true}).Retain(2, nil).Delete(1).InsertEmbed(Embed{"image": "a.png"}, nil)
+	if err := delta.Validate(ValidateOptions{}); err != nil {
+		t.Error("expected no error but got ", err)
+	}
+	if err := New(nil).Validate(ValidateOptions{Document: true}); err != nil {
+		t.Error("expected no error but got ", err)
+	}
+}
+
+func TestValidateMultipleOpKinds(t *testing.T) {
+	one := 1
+	delta := New([]Op{{Insert: []rune("a")}, {Insert: []rune("b"), Delete: &one}})
+	expectOpError(t, delta.Validate(ValidateOptions{}), ErrMultipleOpKinds, 1)
+
+	delta = New([]Op{{Retain: &one, RetainEmbed: Embed{"table": nil}}})
+	expectOpError(t, delta.Validate(ValidateOptions{}), ErrMultipleOpKinds, 0)
+}
+
+func TestValidateNonPositiveLength(t *testing.T) {
+	zero, negative := 0, -2
+	cases := []Op{
+		{Retain: &zero},
+		{Retain: &negative},
+		{Delete: &zero},
+		{Insert: []rune{}},
+		{Attributes: AttributeMap{"bold": true}},
+	}
+	for _, op := range cases {
+		delta := New([]Op{{Insert: []rune("a")}, op})
+		expectOpError(t, delta.Validate(ValidateOptions{}), ErrNonPositiveLength, 1)
+	}
+}
+
+func TestValidateDocument(t *testing.T) {
+	delta := New(nil).Insert("a", nil).InsertEmbed(Embed{"image": "a.png"}, nil).Retain(1, nil)
+	if err := delta.Validate(ValidateOptions{}); err != nil {
+		t.Error("expected no error but got ", err)
+	}
+	expectOpError(t, delta.Validate(ValidateOptions{Document: true}), ErrNotADocument, 2)
+}
+
+func TestIsDocument(t *testing.T) {
+	if !New(nil).Insert("a", nil).InsertEmbed(Embed{"image": "a.png"}, nil).IsDocument() {
+		t.Error("expected inserts to be a document")
+	}
+	if !New(nil).IsDocument() {
+		t.Error("expected an empty delta to be a document")
+	}
+	if New(nil).Insert("a", nil).Delete(1).IsDocument() {
+		t.Error("expected a delete to not be a document")
+	}
+}
+
+func TestApplyTo(t *testing.T) {
+	doc := New(nil).Insert("Hello", nil)
+	change := New(nil).Retain(4, nil).Delete(1).Insert("!", nil)
+	expected := New(nil).Insert("Hell!", nil)
+	x, err := change.ApplyTo(*doc)
+	if err != nil {
+		t.Fatal("failed with ", err)
+	}
+	if !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}
+
+func TestApplyToPastEnd(t *testing.T) {
+	doc := New(nil).Insert("Hello", nil)
+	_, err := New(nil).Retain(3, nil).Insert("a", nil).Delete(3).ApplyTo(*doc)
+	expectOpError(t, err, ErrPastEndOfDocument, 2)
+
+	_, err = New(nil).Retain(6, map[string]interface{}{"bold": true}).ApplyTo(*doc)
+	expectOpError(t, err, ErrPastEndOfDocument, 0)
+}
+
+func TestApplyToInvalid(t *testing.T) {
+	_, err := New(nil).Insert("a", nil).ApplyTo(*New(nil).Insert("a", nil).Retain(1, nil))
+	expectOpError(t, err, ErrNotADocument, 1)
+
+	_, err = New([]Op{{}}).ApplyTo(*New(nil).Insert("a", nil))
+	expectOpError(t, err, ErrNonPositiveLength, 0)
+}
+
+func TestApplyToSurrogateSplit(t *testing.T) {
+	doc := New(nil).Insert("😀a", nil)
+	_, err := New(nil).Retain(1, nil).Insert("x", nil).ApplyTo(*doc)
+	expectOpError(t, err, ErrSurrogateSplit, 0)
+
+	x, err := New(nil).Retain(2, nil).Insert("x", nil).ApplyTo(*doc)
+	expected := New(nil).Insert("😀xa", nil)
+	if err != nil || !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v %v\n", expected, x, err)
+	}
+}
+
+func TestApplyToEmbeds(t *testing.T) {
+	doc := New(nil).Insert("a", nil).InsertEmbed(Embed{"foo": "x"}, nil).InsertEmbed(Embed{"delta": []Op{}}, nil)
+
+	_, err := New(nil).Retain(1, nil).RetainEmbed(Embed{"foo": "y"}, nil).ApplyTo(*doc)
+	expectOpError(t, err, ErrNoEmbedHandler, 1)
+
+	_, err = New(nil).RetainEmbed(Embed{"delta": []Op{}}, nil).ApplyTo(*doc)
+	expectOpError(t, err, ErrCannotRetainText, 0)
+
+	RegisterEmbed("delta", deltaEmbed{})
+	defer UnregisterEmbed("delta")
+
+	_, err = New(nil).Retain(1, nil).RetainEmbed(Embed{"delta": []Op{}}, nil).ApplyTo(*doc)
+	expectOpError(t, err, ErrEmbedTypeMismatch, 1)
+
+	x, err := New(nil).Retain(2, nil).RetainEmbed(Embed{"delta": New(nil).Insert("b", nil).Ops}, nil).ApplyTo(*doc)
+	expected := New(nil).Insert("a", nil).InsertEmbed(Embed{"foo": "x"}, nil).InsertEmbed(Embed{"delta": New(nil).Insert("b", nil).Ops}, nil)
+	if err != nil || !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v %v\n", expected, x, err)
+	}
+
+	// the handler can't read the change, Compose would panic
+	if _, err := New(nil).Retain(2, nil).RetainEmbed(Embed{"delta": "b"}, nil).ApplyTo(*doc); err == nil {
+		t.Error("expected an error")
+	}
+}
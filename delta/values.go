@@ -0,0 +1,135 @@
+package delta
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+)
+
+// valuesEqual compares two attribute or embed values the way JSON sees them.
+// Objects and arrays are compared element by element, and numbers by value,
+// so float64(1), 1 and json.Number("1") are all equal
+func valuesEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if x, ok := numberValue(a); ok {
+		y, ok := numberValue(b)
+		return ok && x.Cmp(y) == 0
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch va.Kind() {
+	case reflect.Map:
+		if vb.Kind() != reflect.Map || va.Type().Key().Kind() != reflect.String ||
+			vb.Type().Key().Kind() != reflect.String || va.Len() != vb.Len() {
+			return false
+		}
+		iter := va.MapRange()
+		for iter.Next() {
+			bv := vb.MapIndex(reflect.ValueOf(iter.Key().String()).Convert(vb.Type().Key()))
+			if !bv.IsValid() || !valuesEqual(iter.Value().Interface(), bv.Interface()) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if (vb.Kind() != reflect.Slice && vb.Kind() != reflect.Array) || va.Len() != vb.Len() {
+			return false
+		}
+		for i := 0; i < va.Len(); i++ {
+			if !valuesEqual(va.Index(i).Interface(), vb.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// numberValue returns the exact value of any Go or JSON number
+func numberValue(v interface{}) (*big.Rat, bool) {
+	if n, ok := v.(json.Number); ok {
+		return new(big.Rat).SetString(string(n))
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(rv.Uint())), true
+	case reflect.Float32, reflect.Float64:
+		r := new(big.Rat).SetFloat64(rv.Float())
+		return r, r != nil
+	}
+	return nil, false
+}
+
+// asObject returns v as a map if it holds a JSON object
+func asObject(v interface{}) (map[string]interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v, true
+	case AttributeMap:
+		return v, true
+	case Embed:
+		return v, true
+	}
+	return nil, false
+}
+
+// cloneValue returns a deep copy of the JSON objects and arrays in v, other values are returned as they are
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return cloneObject(v)
+	case AttributeMap:
+		return AttributeMap(cloneObject(v))
+	case Embed:
+		return Embed(cloneObject(v))
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		ret := make([]interface{}, len(v))
+		for i := range v {
+			ret[i] = cloneValue(v[i])
+		}
+		return ret
+	}
+	return v
+}
+
+func cloneObject(v map[string]interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	ret := make(map[string]interface{}, len(v))
+	for k := range v {
+		ret[k] = cloneValue(v[k])
+	}
+	return ret
+}
+
+// mergeObjects composes b on top of a key by key, merging nested objects the same way.
+// Unless keepNil is true, nil values on b remove the key, and objects left empty are removed too
+func mergeObjects(a, b map[string]interface{}, keepNil bool) map[string]interface{} {
+	ret := make(map[string]interface{})
+	for k, bv := range b {
+		av, aFound := a[k]
+		aObj, aIsObject := asObject(av)
+		bObj, bIsObject := asObject(bv)
+		if aFound && aIsObject && bIsObject {
+			if merged := mergeObjects(aObj, bObj, keepNil); keepNil || len(merged) > 0 {
+				ret[k] = merged
+			}
+		} else if keepNil || bv != nil {
+			ret[k] = cloneValue(bv)
+		}
+	}
+	for k, av := range a {
+		if _, bFound := b[k]; !bFound && (keepNil || av != nil) {
+			ret[k] = cloneValue(av)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,68 @@
+package delta
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValuesEqualNumbers(t *testing.T) {
+	if !valuesEqual(float64(1), 1) {
+		t.Error("expected float64(1) to equal 1")
+	}
+	if !valuesEqual(json.Number("1.50"), 1.5) {
+		t.Error("expected json.Number(1.50) to equal 1.5")
+	}
+	if !valuesEqual(json.Number("12345678901234567890"), uint64(12345678901234567890)) {
+		t.Error("expected json.Number(12345678901234567890) to equal the uint64")
+	}
+	if valuesEqual(json.Number("12345678901234567891"), json.Number("12345678901234567890")) {
+		t.Error("expected large json.Numbers to be compared exactly")
+	}
+	if valuesEqual(1, "1") {
+		t.Error("expected 1 not to equal '1'")
+	}
+}
+
+func TestValuesEqualObjects(t *testing.T) {
+	a := map[string]interface{}{"family": "Helvetica", "size": []interface{}{float64(15), "px"}}
+	b := AttributeMap{"size": []interface{}{15, "px"}, "family": "Helvetica"}
+	if !valuesEqual(a, b) {
+		t.Errorf("expected %+v to equal %+v\n", a, b)
+	}
+	c := map[string]interface{}{"family": "Helvetica", "size": []interface{}{float64(16), "px"}}
+	if valuesEqual(a, c) {
+		t.Errorf("expected %+v not to equal %+v\n", a, c)
+	}
+	if valuesEqual(a, map[string]interface{}{"family": "Helvetica"}) {
+		t.Error("expected objects with different keys not to be equal")
+	}
+	if valuesEqual([]interface{}{1, 2}, []interface{}{1}) {
+		t.Error("expected arrays with different lengths not to be equal")
+	}
+	if valuesEqual(map[string]interface{}{}, nil) {
+		t.Error("expected an empty object not to equal nil")
+	}
+}
+
+func TestCloneValue(t *testing.T) {
+	v := map[string]interface{}{"list": []interface{}{map[string]interface{}{"a": 1}}}
+	x := cloneValue(v).(map[string]interface{})
+	x["list"].([]interface{})[0].(map[string]interface{})["a"] = 2
+	if !reflect.DeepEqual(map[string]interface{}{"list": []interface{}{map[string]interface{}{"a": 1}}}, v) {
+		t.Errorf("expected the original to be unchanged but got %+v\n", v)
+	}
+}
+
+func TestMergeObjects(t *testing.T) {
+	a := map[string]interface{}{"style": map[string]interface{}{"color": "red", "size": "12px"}, "bold": true}
+	b := map[string]interface{}{"style": map[string]interface{}{"color": "blue", "size": nil, "font": "serif"}}
+	expected := map[string]interface{}{"style": map[string]interface{}{"color": "blue", "font": "serif"}, "bold": true}
+	if x := mergeObjects(a, b, false); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+	expected = map[string]interface{}{"style": map[string]interface{}{"color": "blue", "size": nil, "font": "serif"}, "bold": true}
+	if x := mergeObjects(a, b, true); !reflect.DeepEqual(expected, x) {
+		t.Errorf("expected %+v but got %+v\n", expected, x)
+	}
+}